@@ -21,15 +21,36 @@ type Literal struct {
 	Value reflect.Value
 }
 
+//Scope is kind of variable decided by resolver
+type Scope int
+
+const (
+	//Dynamic variable is looked up by name at runtime. it's default
+	Dynamic Scope = iota
+	//Local variable is slot Index of Env Depth levels up
+	Local
+	//Global variable is defined at top level
+	Global
+)
+
+//Resolved is result of scope resolution
+type Resolved struct {
+	Scope Scope
+	Depth int
+	Index int
+}
+
 //RefVar is reference of variable
 type RefVar struct {
 	ExprImpl
+	Resolved
 	Identifer string
 }
 
 //BindVar is Variable Binding. eg a=1
 type BindVar struct {
 	ExprImpl
+	Resolved
 	Identifer string
 	Expr      Expr
 }
@@ -37,6 +58,7 @@ type BindVar struct {
 //Funcall is function call
 type Funcall struct {
 	ExprImpl
+	Resolved
 	Identifer string
 	Args      []Expr
 }
@@ -54,6 +76,8 @@ type Block struct {
 	ExprImpl
 	FormalArgments []string
 	Body           []Expr
+	//Slots is number of local variables including formal argments. it's set by resolver
+	Slots int
 }
 
 //If is if expression
@@ -69,6 +93,9 @@ type While struct {
 	ExprImpl
 	Cond []Expr
 	Body []Expr
+	//CondSlots and BodySlots are numbers of local variables. they are set by resolver
+	CondSlots int
+	BodySlots int
 }
 
 // Array is array.
@@ -77,6 +104,35 @@ type Array struct {
 	Elements []Expr
 }
 
+// Map is map literal. eg {a: 1, "b": 2}
+type Map struct {
+	ExprImpl
+	Keys   []Expr
+	Values []Expr
+}
+
+// StringInterp is string literal which has interpolation. eg "value=${x}"
+// Parts are string literals and expressions, concatenated after evaluation.
+type StringInterp struct {
+	ExprImpl
+	Parts []Expr
+}
+
+// Index is index expression. eg a[0]
+type Index struct {
+	ExprImpl
+	Target Expr
+	Index  Expr
+}
+
+// Slice is slice expression. eg a[1:3]. From and To are nil if omitted.
+type Slice struct {
+	ExprImpl
+	Target Expr
+	From   Expr
+	To     Expr
+}
+
 // Wait is wait expression
 type Wait struct {
 	ExprImpl
@@ -88,6 +144,14 @@ type Close struct {
 	Ret []Expr
 }
 
+// Import is import expression. eg import "lib/stream.nstrm" as stream
+// Alias is empty if bindings are imported without namespace.
+type Import struct {
+	ExprImpl
+	Path  string
+	Alias string
+}
+
 // Emit is emit expression
 type Emit struct {
 	ExprImpl
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"fmt"
+	"reflect"
 	"sync"
 	"testing"
 
-	"io/ioutil"
-	"log"
-
 	"./builtins"
 	"./parser"
 	"./vm"
@@ -36,7 +35,6 @@ func bRun(expr string, t *testing.B) {
 	p.Execute()
 	env := vm.NewEnv(&wg)
 	builtins.LoadCore(env)
-	log.SetOutput(ioutil.Discard)
 	if r, err := p.Run(env); err == nil {
 		env.Decref()
 		env.RunWait(r)
@@ -162,3 +160,91 @@ func BenchmarkPrime(b *testing.B) {
 	`
 	bRun(prog, b)
 }
+
+//bCompare runs expr b.N times with run. it's for comparing tree-walking interpreter with compiled code
+func bCompare(expr string, b *testing.B, run func(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue)) {
+	p := &parser.Nstrm{Buffer: expr}
+	p.Init()
+	p.MyParser.Init()
+	if err := p.Parse(); err != nil {
+		b.Fatal("Parser Error")
+	}
+	p.Execute()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		//Env and builtins are not part of the comparison
+		b.StopTimer()
+		var wg sync.WaitGroup
+		env := vm.NewEnv(&wg)
+		builtins.LoadCore(env)
+		b.StartTimer()
+		r, err := run(p, env)
+		if err != nil {
+			b.Fatal(err)
+		}
+		env.RunWait(r)
+		env.Decref()
+		wg.Wait()
+	}
+}
+
+func interpret(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue) {
+	return p.Interpret(env)
+}
+
+func compiled(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue) {
+	return p.Run(env)
+}
+
+const benchCall = `fib={n->if n<2 {n}else{fib(n-1)+fib(n-2)}};fib(15)`
+
+const benchLoop = `
+f = {n ->
+  i = 0
+  s = 0
+  while i < n {
+    s = s + i * i
+    i = i + 1
+  }
+  s
+}
+f(2000)
+`
+
+func BenchmarkCallTree(b *testing.B) {
+	bCompare(benchCall, b, interpret)
+}
+
+func BenchmarkCallCompiled(b *testing.B) {
+	bCompare(benchCall, b, compiled)
+}
+
+func BenchmarkLoopTree(b *testing.B) {
+	bCompare(benchLoop, b, interpret)
+}
+
+func BenchmarkLoopCompiled(b *testing.B) {
+	bCompare(benchLoop, b, compiled)
+}
+
+//benchPmap maps heavy function over 32 values with pmap. %d is number of workers
+const benchPmap = `
+work = {x ->
+  i = 0
+  s = 0
+  while i < 500 {
+    s = s + i * x
+    i = i + 1
+  }
+  s
+}
+seq(32) | pmap(work,%d) | last()
+`
+
+func BenchmarkPmap1(b *testing.B) {
+	bCompare(fmt.Sprintf(benchPmap, 1), b, compiled)
+}
+
+func BenchmarkPmap4(b *testing.B) {
+	bCompare(fmt.Sprintf(benchPmap, 4), b, compiled)
+}
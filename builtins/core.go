@@ -23,10 +23,20 @@ func helper(fun func(reflect.Value, reflect.Value) (reflect.Value, error)) refle
 	}))
 }
 
+//defineGo defines Go function f. arguments and result are converted by vm.NewGoFunction
+func defineGo(env *vm.Env, name string, f interface{}) {
+	env.DefineBuiltin(name, reflect.ValueOf(vm.MustGoFunction(f)))
+}
+
 //LoadCore defines core function to env
 func LoadCore(env *vm.Env) {
 	LoadIO(env)
 	LoadUtil(env)
+	LoadMap(env)
+	LoadFile(env)
+	LoadString(env)
+	LoadStream(env)
+	LoadTime(env)
 
 	env.DefineBuiltin("append", helper(func(arr, elem reflect.Value) (reflect.Value, error) {
 		switch a := arr.Interface().(type) {
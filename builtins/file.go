@@ -0,0 +1,99 @@
+package builtins
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"../ast"
+	"../pipe"
+	"../vm"
+)
+
+//readLines sends lines of f to v without newline. read error is reported to env
+func readLines(env *vm.Env, pos ast.Pos, f *os.File, v pipe.Valve) {
+	defer func() {
+		f.Close()
+		v.Close()
+	}()
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if !v.Send(reflect.ValueOf(strings.TrimRight(line, "\r\n"))) {
+				return
+			}
+		}
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			env.ReportError(vm.Errorf(pos, "%s", err))
+			return
+		}
+	}
+}
+
+//writeLines returns consumer function which opens path with flag and writes each value to it as a line.
+//file is opened when the consumer runs. open and write error are reported to env
+func writeLines(env *vm.Env, pos ast.Pos, path string, flag int) func(<-chan reflect.Value) reflect.Value {
+	return func(r <-chan reflect.Value) reflect.Value {
+		f, err := os.OpenFile(path, flag, 0666)
+		if err != nil {
+			env.ReportError(vm.Errorf(pos, "%s", err))
+			return vm.NIL
+		}
+		defer f.Close()
+		writer := bufio.NewWriter(f)
+		for v := range r {
+			if _, err := fmt.Fprintln(writer, vm.ToString(v)); err != nil {
+				env.ReportError(vm.Errorf(pos, "%s", err))
+				return vm.NIL
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			env.ReportError(vm.Errorf(pos, "%s", err))
+		}
+		return vm.NIL
+	}
+}
+
+func getPath(args []reflect.Value) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("wrong number of argments")
+	}
+	return getString(args[0])
+}
+
+//openWriter defines consumer builtin which writes to file opened with flag
+func openWriter(env *vm.Env, name string, flag int) {
+	env.DefineBuiltin(name, reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		path, err := getPath(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewConsumer(writeLines(env, pos, path, flag))), nil
+	})))
+}
+
+//LoadFile defines file function
+func LoadFile(env *vm.Env) {
+	env.DefineBuiltin("fread", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		path, err := getPath(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return vm.NIL, err
+		}
+		out := pipe.NewContextValve(env.Context())
+		go readLines(env, pos, f, out)
+		return reflect.ValueOf(pipe.NewProducer(out)), nil
+	})))
+
+	openWriter(env, "fwrite", os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	openWriter(env, "fappend", os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+}
@@ -0,0 +1,100 @@
+package builtins
+
+import (
+	"fmt"
+	"reflect"
+
+	"../vm"
+)
+
+func getMap(v reflect.Value) (*vm.Map, error) {
+	if v.IsValid() {
+		if m, ok := v.Interface().(*vm.Map); ok {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not map", vm.Inspect(v))
+}
+
+//LoadMap defines functions for map
+func LoadMap(env *vm.Env) {
+	env.DefineBuiltin("get", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		m, err := getMap(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		if v, ok := m.Get(args[1]); ok {
+			return v, nil
+		}
+		if len(args) == 3 {
+			return args[2], nil
+		}
+		return vm.NIL, nil
+	})))
+
+	env.DefineBuiltin("has", helper(func(arg, key reflect.Value) (reflect.Value, error) {
+		m, err := getMap(arg)
+		if err != nil {
+			return vm.NIL, err
+		}
+		_, ok := m.Get(key)
+		return reflect.ValueOf(ok), nil
+	}))
+
+	env.DefineBuiltin("put", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 3 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		m, err := getMap(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		ret, err := m.Set(args[1], args[2])
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(ret), nil
+	})))
+
+	env.DefineBuiltin("delete", helper(func(arg, key reflect.Value) (reflect.Value, error) {
+		m, err := getMap(arg)
+		if err != nil {
+			return vm.NIL, err
+		}
+		ret, err := m.Delete(key)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(ret), nil
+	}))
+
+	env.DefineBuiltin("keys", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 1 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		m, err := getMap(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(m.Keys()), nil
+	})))
+
+	env.DefineBuiltin("values", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 1 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		m, err := getMap(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		ret := []reflect.Value{}
+		m.Each(func(key, value reflect.Value) bool {
+			ret = append(ret, value)
+			return true
+		})
+		return reflect.ValueOf(ret), nil
+	})))
+}
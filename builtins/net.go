@@ -1,71 +1,239 @@
 package builtins
 
 import (
+	"bufio"
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"net"
 	"reflect"
+	"strings"
+	"sync"
 
 	"../pipe"
 	"../vm"
 )
 
-//LoadNet defines net function
+//connection modes. it decides unit of values read from connection
+const (
+	modeBytes  = "bytes"
+	modeLines  = "lines"
+	modeChunks = "chunks"
+)
+
+func getMode(args []reflect.Value, i int) (string, error) {
+	if len(args) <= i {
+		return modeBytes, nil
+	}
+	mode, err := getString(args[i])
+	if err != nil {
+		return "", err
+	}
+	switch mode {
+	case modeBytes, modeLines, modeChunks:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %s. it must be bytes, lines or chunks", mode)
+}
+
+//readConn sends values read from conn to v until conn is closed
+func readConn(conn net.Conn, v pipe.Valve, mode string, done func()) {
+	defer func() {
+		v.Close()
+		done()
+	}()
+	switch mode {
+	case modeLines:
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				if !v.Send(reflect.ValueOf(strings.TrimRight(line, "\r\n"))) {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	case modeChunks:
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if !v.Send(reflect.ValueOf(string(buf[:n]))) {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	default:
+		buf := make([]byte, 4096)
+		for {
+			n, err := conn.Read(buf)
+			for i := 0; i < n; i++ {
+				if !v.Send(reflect.ValueOf(buf[i])) {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+//writeConn writes values from r to conn. bytes are written as it is, others are written as string.
+//in lines mode, each value is followed by newline. write side of conn is closed when r is closed.
+func writeConn(conn net.Conn, mode string, done func()) func(<-chan reflect.Value) reflect.Value {
+	return func(r <-chan reflect.Value) reflect.Value {
+		defer done()
+		for v := range r {
+			var buf []byte
+			switch t := v.Interface().(type) {
+			case byte:
+				buf = []byte{t}
+			default:
+				buf = []byte(vm.ToString(v))
+			}
+			if mode == modeLines {
+				buf = append(buf, '\n')
+			}
+			if _, err := conn.Write(buf); err != nil {
+				conn.Close()
+				return vm.NIL
+			}
+		}
+		//no more input. tell it to peer but keep reading
+		if c, ok := conn.(interface {
+			CloseWrite() error
+		}); ok {
+			c.CloseWrite()
+		}
+		return vm.NIL
+	}
+}
+
+//connstate closes conn when both reading and writing are end.
+//if nothing is connected to write to conn, it's closed when reading is end
+type connstate struct {
+	conn    net.Conn
+	writing bool
+	read    bool
+	written bool
+	mutex   sync.Mutex
+	once    sync.Once
+	end     chan bool
+}
+
+func (c *connstate) close() {
+	c.once.Do(func() {
+		close(c.end)
+		c.conn.Close()
+	})
+}
+
+func (c *connstate) readDone() {
+	c.mutex.Lock()
+	c.read = true
+	end := !c.writing || c.written
+	c.mutex.Unlock()
+	if end {
+		c.close()
+	}
+}
+
+func (c *connstate) attach() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.writing = true
+}
+
+func (c *connstate) writeDone() {
+	c.mutex.Lock()
+	c.written = true
+	end := c.read
+	c.mutex.Unlock()
+	if end {
+		c.close()
+	}
+}
+
+//writer is Consumer which writes to connection. it tells connstate that something is connected to it
+type writer struct {
+	pipe.Consumer
+	state *connstate
+}
+
+func (w *writer) NewR() pipe.Valve {
+	w.state.attach()
+	return w.Consumer.NewR()
+}
+
+//connection creates Handle to read from and write to conn.
+//conn is closed when both reading and writing are end, or ctx is done.
+//if nothing is connected to write to it, conn is closed when reading is end
+func connection(ctx context.Context, conn net.Conn, mode string) pipe.Handle {
+	state := &connstate{conn: conn, end: make(chan bool)}
+	go func() {
+		select {
+		case <-state.end:
+		case <-ctx.Done():
+			state.close()
+		}
+	}()
+	producer := pipe.NewContextValve(ctx)
+	go readConn(conn, producer, mode, state.readDone)
+	i := &writer{Consumer: pipe.NewConsumer(writeConn(conn, mode, state.writeDone)), state: state}
+	o := pipe.NewProducer(producer)
+	io := pipe.InOut(i, o)
+	i.Decref()
+	o.Decref()
+	return io
+}
+
+//LoadNet defines net functions
 func LoadNet(env *vm.Env) {
 	env.DefineBuiltin("tcp_server", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 1 && len(args) != 2 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
 		port, ok := vm.GetInt(args[0])
 		if !ok {
-			return vm.NIL, fmt.Errorf("%v is not number", port)
+			return vm.NIL, fmt.Errorf("%s is not number", vm.Inspect(args[0]))
+		}
+		mode, err := getMode(args, 1)
+		if err != nil {
+			return vm.NIL, err
 		}
-		out := pipe.NewValve()
+		ctx := env.Context()
+		out := pipe.NewContextValve(ctx)
 		if ln, err := net.Listen("tcp", fmt.Sprintf(":%d", port)); err == nil {
+			end := make(chan bool)
+			//close listener to unblock Accept when cancelled
 			go func() {
+				select {
+				case <-end:
+				case <-ctx.Done():
+					ln.Close()
+				}
+			}()
+			go func() {
+				defer close(end)
 				for {
-					connection, err := ln.Accept()
+					conn, err := ln.Accept()
 					if err == nil {
-						producer := pipe.NewValve()
-						go func(conn net.Conn, v pipe.Valve) {
-							buf := make([]byte, 4096)
-							for {
-								if n, err := conn.Read(buf); err == nil {
-									for i := 0; i < n; i++ {
-										v.Send(reflect.ValueOf(buf[i]))
-									}
-								} else if err == io.EOF {
-									conn.Close()
-									producer.Close()
-									return
-								}
-							}
-						}(connection, producer)
-
-						consumer := func(conn net.Conn) func(<-chan reflect.Value) reflect.Value {
-							return func(r <-chan reflect.Value) reflect.Value {
-								for v := range r {
-									switch t := v.Interface().(type) {
-									case byte:
-										if _, err := conn.Write([]byte{t}); err != nil {
-											conn.Close()
-											break
-										}
-									default:
-										log.Println("unimplemented")
-									}
-								}
-								return vm.NIL
-							}
-						}(connection)
-						i := pipe.NewConsumer(consumer)
-						o := pipe.NewProducer(producer)
-						io := pipe.InOut(i, o)
-						i.Decref()
-						o.Decref()
+						io := connection(ctx, conn, mode)
 						if !out.Send(reflect.ValueOf(io)) {
 							io.Decref()
 							out.Close()
+							ln.Close()
 							return
 						}
+					} else if ctx.Err() != nil {
+						out.Close()
+						return
 					}
 				}
 			}()
@@ -74,4 +242,27 @@ func LoadNet(env *vm.Env) {
 			return vm.NIL, err
 		}
 	})))
+	env.DefineBuiltin("tcp_connect", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 2 && len(args) != 3 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		host, err := getString(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		port, ok := vm.GetInt(args[1])
+		if !ok {
+			return vm.NIL, fmt.Errorf("%s is not number", vm.Inspect(args[1]))
+		}
+		mode, err := getMode(args, 2)
+		if err != nil {
+			return vm.NIL, err
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(env.Context(), "tcp", net.JoinHostPort(host, fmt.Sprint(port)))
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(connection(env.Context(), conn, mode)), nil
+	})))
 }
@@ -0,0 +1,502 @@
+package builtins
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+
+	"../ast"
+	"../pipe"
+	"../vm"
+)
+
+//getSize converts args[0] to positive integer
+func getSize(args []reflect.Value, name string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of argments")
+	}
+	n, ok := vm.GetInt(args[0])
+	if !ok || n < 1 {
+		return 0, fmt.Errorf("%s must be positive integer. got %s", name, vm.Inspect(args[0]))
+	}
+	return int(n), nil
+}
+
+//getCount converts args[0] to non-negative integer
+func getCount(args []reflect.Value) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of argments")
+	}
+	n, ok := vm.GetInt(args[0])
+	if !ok || n < 0 {
+		return 0, fmt.Errorf("count must be non-negative integer. got %s", vm.Inspect(args[0]))
+	}
+	return int(n), nil
+}
+
+//sendArray sends copy of values as array
+func sendArray(w pipe.Valve, values []reflect.Value) bool {
+	arr := make([]reflect.Value, len(values))
+	copy(arr, values)
+	return w.Send(reflect.ValueOf(arr))
+}
+
+//getFunction converts args[i] to Function
+func getFunction(args []reflect.Value, i int) (vm.Function, error) {
+	if args[i].IsValid() {
+		if f, ok := args[i].Interface().(vm.Function); ok {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not function", vm.Inspect(args[i]))
+}
+
+//call calls f. if f fails, error is reported to env and ok is false
+func call(env *vm.Env, pos ast.Pos, f vm.Function, args ...reflect.Value) (ret reflect.Value, ok bool) {
+	ret, err := f.Call(pos, args, pipe.NilValve())
+	switch E := err.(type) {
+	case nil:
+		return ret, true
+	case *vm.Error:
+		env.ReportError(E)
+	default:
+		env.ReportError(vm.Errorf(pos, "%T is not allowed here", E))
+	}
+	return vm.NIL, false
+}
+
+//test calls predicate f and converts its result to bool
+func test(env *vm.Env, pos ast.Pos, f vm.Function, v reflect.Value) (result bool, ok bool) {
+	ret, ok := call(env, pos, f, v)
+	if !ok {
+		return false, false
+	}
+	if ret.IsValid() {
+		if b, isbool := ret.Interface().(bool); isbool {
+			return b, true
+		}
+		env.ReportError(vm.Errorf(pos, "%s is not bool", vm.Inspect(ret)))
+		return false, false
+	}
+	return false, true
+}
+
+//getProducers converts all args to Producer. the producers belong to scope of env
+func getProducers(env *vm.Env, pos ast.Pos, args []reflect.Value) ([]pipe.Producer, error) {
+	ps := make([]pipe.Producer, len(args))
+	for i, arg := range args {
+		p, ok := vm.AsProducer(pos, arg, env)
+		if !ok {
+			return nil, fmt.Errorf("%s is not producer", vm.Inspect(arg))
+		}
+		ps[i] = p
+	}
+	return ps, nil
+}
+
+//source reads values from Producer
+type source struct {
+	p pipe.Producer
+	v pipe.Valve
+}
+
+//open connects p to new valve and runs p. nothing else is connected to p after it
+func open(p pipe.Producer) *source {
+	v := pipe.NewValve()
+	p.AddW(v)
+	var wg sync.WaitGroup
+	p.Run(&wg)
+	p.NotifyExit()
+	return &source{p: p, v: v}
+}
+
+//next returns next value. ok is false at the end of stream
+func (s *source) next() (reflect.Value, bool) {
+	v, ok := s.v.Receive()
+	if !ok || pipe.IsEOF(v) {
+		return pipe.EOF, false
+	}
+	return v, true
+}
+
+//stop tells upstream not to send anymore
+func (s *source) stop() {
+	s.v.Close()
+	s.p.NotifyExit()
+}
+
+//newProducer creates Producer which holds ps while f sends values to out
+func newProducer(ps []pipe.Producer, f func(out pipe.Valve)) pipe.Producer {
+	for _, p := range ps {
+		p.Incref()
+	}
+	out := pipe.NewValve()
+	go func() {
+		defer func() {
+			out.Close()
+			for _, p := range ps {
+				p.Decref()
+			}
+		}()
+		f(out)
+	}()
+	return pipe.NewProducer(out)
+}
+
+//newConsumer creates Consumer which calls f with each value. f returns false to stop.
+func newConsumer(init reflect.Value, f func(acc reflect.Value, v reflect.Value) (reflect.Value, bool)) pipe.Consumer {
+	return pipe.NewConsumer(func(r <-chan reflect.Value) reflect.Value {
+		acc := init
+		for v := range r {
+			var ok bool
+			if acc, ok = f(acc, v); !ok {
+				return vm.NIL
+			}
+		}
+		return acc
+	})
+}
+
+//LoadStream defines stream function
+func LoadStream(env *vm.Env) {
+	env.DefineBuiltin("pmap", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) < 1 || len(args) > 3 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		f, err := getFunction(args, 0)
+		if err != nil {
+			return vm.NIL, err
+		}
+		n := int64(runtime.GOMAXPROCS(0))
+		if len(args) >= 2 {
+			var ok bool
+			if n, ok = vm.GetInt(args[1]); !ok || n < 1 {
+				return vm.NIL, fmt.Errorf("number of workers must be positive integer. got %s", vm.Inspect(args[1]))
+			}
+		}
+		ordered := false
+		if len(args) == 3 {
+			var isbool bool
+			if args[2].IsValid() {
+				ordered, isbool = args[2].Interface().(bool)
+			}
+			if !isbool {
+				return vm.NIL, fmt.Errorf("%s is not bool", vm.Inspect(args[2]))
+			}
+		}
+		return reflect.ValueOf(vm.ParallelFilter(pos, f, int(n), ordered, env)), nil
+	})))
+	env.DefineBuiltin("batch", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		n, err := getSize(args, "size")
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			buf := make([]reflect.Value, 0, n)
+			for v := range r {
+				buf = append(buf, vm.Eval(v))
+				if len(buf) == n {
+					if !sendArray(w, buf) {
+						return
+					}
+					buf = buf[:0]
+				}
+			}
+			if len(buf) > 0 {
+				sendArray(w, buf)
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("sliding", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		n, err := getSize(args, "size")
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			buf := make([]reflect.Value, 0, n)
+			for v := range r {
+				if len(buf) == n {
+					buf = append(buf[:0], buf[1:]...)
+				}
+				buf = append(buf, vm.Eval(v))
+				if len(buf) == n {
+					if !sendArray(w, buf) {
+						return
+					}
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("window", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		ms, err := getSize(args, "milliseconds")
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			ticker := time.NewTicker(time.Duration(ms) * time.Millisecond)
+			defer ticker.Stop()
+			buf := []reflect.Value{}
+			for {
+				select {
+				case v, ok := <-r:
+					if !ok {
+						if len(buf) > 0 {
+							sendArray(w, buf)
+						}
+						return
+					}
+					buf = append(buf, vm.Eval(v))
+				case <-ticker.C:
+					if len(buf) > 0 {
+						if !sendArray(w, buf) {
+							return
+						}
+						buf = buf[:0]
+					}
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("take", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		n, err := getCount(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			if n == 0 {
+				return
+			}
+			i := 0
+			for v := range r {
+				if !w.Send(v) {
+					return
+				}
+				if i++; i == n {
+					return
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("drop", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		n, err := getCount(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			i := 0
+			for v := range r {
+				if i < n {
+					i++
+					continue
+				}
+				if !w.Send(v) {
+					return
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("takewhile", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 1 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		f, err := getFunction(args, 0)
+		if err != nil {
+			return vm.NIL, err
+		}
+		f.Incref()
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			defer f.Decref()
+			for v := range r {
+				if b, ok := test(env, pos, f, v); !ok || !b {
+					return
+				}
+				if !w.Send(v) {
+					return
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("dropwhile", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 1 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		f, err := getFunction(args, 0)
+		if err != nil {
+			return vm.NIL, err
+		}
+		f.Incref()
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			defer f.Decref()
+			dropping := true
+			for v := range r {
+				if dropping {
+					b, ok := test(env, pos, f, v)
+					if !ok {
+						return
+					}
+					if dropping = b; dropping {
+						continue
+					}
+				}
+				if !w.Send(v) {
+					return
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("fold", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 2 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		f, err := getFunction(args, 0)
+		if err != nil {
+			return vm.NIL, err
+		}
+		f.Incref()
+		return reflect.ValueOf(pipe.NewConsumer(func(r <-chan reflect.Value) reflect.Value {
+			defer f.Decref()
+			acc := args[1]
+			for v := range r {
+				var ok bool
+				if acc, ok = call(env, pos, f, acc, v); !ok {
+					return vm.NIL
+				}
+			}
+			return acc
+		})), nil
+	})))
+
+	env.DefineBuiltin("sum", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		return reflect.ValueOf(newConsumer(reflect.ValueOf(vm.NewInt(0)), func(acc reflect.Value, v reflect.Value) (reflect.Value, bool) {
+			ret, err := vm.AddV(acc, v)
+			if err != nil {
+				env.ReportError(vm.Errorf(pos, "can't sum %s", vm.Inspect(v)))
+				return vm.NIL, false
+			}
+			return ret, true
+		})), nil
+	})))
+
+	env.DefineBuiltin("count", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		return reflect.ValueOf(newConsumer(reflect.ValueOf(vm.NewInt(0)), func(acc reflect.Value, v reflect.Value) (reflect.Value, bool) {
+			return reflect.ValueOf(vm.NewInt(acc.Interface().(vm.Number).ToInt() + 1)), true
+		})), nil
+	})))
+
+	//minmax defines min or max. sign is sign of comparison result to replace
+	minmax := func(name string, sign int) {
+		env.DefineBuiltin(name, reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+			return reflect.ValueOf(newConsumer(vm.NIL, func(acc reflect.Value, v reflect.Value) (reflect.Value, bool) {
+				if !acc.IsValid() {
+					return v, true
+				}
+				cmp, err := vm.CmpV(v, acc)
+				if err != nil {
+					env.ReportError(vm.Errorf(pos, "can't compare %s and %s", vm.Inspect(v), vm.Inspect(acc)))
+					return vm.NIL, false
+				}
+				if cmp*sign > 0 {
+					return v, true
+				}
+				return acc, true
+			})), nil
+		})))
+	}
+	minmax("min", -1)
+	minmax("max", 1)
+
+	env.DefineBuiltin("zip", reflect.ValueOf(vm.NewBuiltinFunctionIn(env, func(caller *vm.Env, pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) < 2 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		ps, err := getProducers(caller, pos, args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(newProducer(ps, func(out pipe.Valve) {
+			srcs := make([]*source, len(ps))
+			for i, p := range ps {
+				srcs[i] = open(p)
+			}
+			defer func() {
+				for _, src := range srcs {
+					src.stop()
+				}
+			}()
+			for {
+				arr := make([]reflect.Value, len(srcs))
+				for i, src := range srcs {
+					v, ok := src.next()
+					if !ok {
+						return
+					}
+					arr[i] = v
+				}
+				if !out.Send(reflect.ValueOf(arr)) {
+					return
+				}
+			}
+		})), nil
+	})))
+
+	env.DefineBuiltin("merge", reflect.ValueOf(vm.NewBuiltinFunctionIn(env, func(caller *vm.Env, pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		ps, err := getProducers(caller, pos, args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(newProducer(ps, func(out pipe.Valve) {
+			var wg sync.WaitGroup
+			wg.Add(len(ps))
+			for _, p := range ps {
+				go func(src *source) {
+					defer func() {
+						src.stop()
+						wg.Done()
+					}()
+					for {
+						v, ok := src.next()
+						if !ok || !out.Send(v) {
+							return
+						}
+					}
+				}(open(p))
+			}
+			wg.Wait()
+		})), nil
+	})))
+
+	env.DefineBuiltin("concat", reflect.ValueOf(vm.NewBuiltinFunctionIn(env, func(caller *vm.Env, pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		ps, err := getProducers(caller, pos, args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(newProducer(ps, func(out pipe.Valve) {
+			for i, p := range ps {
+				src := open(p)
+				for {
+					v, ok := src.next()
+					if !ok {
+						break
+					}
+					if !out.Send(v) {
+						src.stop()
+						for _, rest := range ps[i+1:] {
+							rest.NotifyExit()
+						}
+						return
+					}
+				}
+				src.stop()
+			}
+		})), nil
+	})))
+}
@@ -0,0 +1,287 @@
+package builtins
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	"../vm"
+)
+
+//maxRegexps is the number of compiled patterns kept in cache
+const maxRegexps = 256
+
+//regexps caches recently used patterns. least recently used one is dropped when it's full
+var regexps = struct {
+	sync.Mutex
+	order    *list.List
+	patterns map[string]*list.Element
+}{
+	order:    list.New(),
+	patterns: make(map[string]*list.Element),
+}
+
+//getRegexp compiles pattern. compiled one is cached
+func getRegexp(v reflect.Value) (*regexp.Regexp, error) {
+	pattern, err := getString(v)
+	if err != nil {
+		return nil, err
+	}
+	regexps.Lock()
+	defer regexps.Unlock()
+	if e, ok := regexps.patterns[pattern]; ok {
+		regexps.order.MoveToFront(e)
+		return e.Value.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexps.patterns[pattern] = regexps.order.PushFront(re)
+	if regexps.order.Len() > maxRegexps {
+		oldest := regexps.order.Back()
+		regexps.order.Remove(oldest)
+		delete(regexps.patterns, oldest.Value.(*regexp.Regexp).String())
+	}
+	return re, nil
+}
+
+func checkArgs(args []reflect.Value, min int, max int) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("wrong number of argments")
+	}
+	return nil
+}
+
+//getStrings checks number of args and converts them to string
+func getStrings(args []reflect.Value, min int, max int) ([]string, error) {
+	if err := checkArgs(args, min, max); err != nil {
+		return nil, err
+	}
+	ret := make([]string, len(args))
+	for i, v := range args {
+		s, err := getString(v)
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = s
+	}
+	return ret, nil
+}
+
+func stringArray(strs []string) reflect.Value {
+	ret := make([]reflect.Value, len(strs))
+	for i, s := range strs {
+		ret[i] = reflect.ValueOf(s)
+	}
+	return reflect.ValueOf(ret)
+}
+
+func defineString(env *vm.Env, name string, min int, max int, f func(args []string) (reflect.Value, error)) {
+	env.DefineBuiltin(name, reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		strs, err := getStrings(args, min, max)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return f(strs)
+	})))
+}
+
+//LoadString defines string function
+func LoadString(env *vm.Env) {
+	env.DefineBuiltin("upper", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return vm.NIL, err
+		}
+		if !args[0].IsValid() {
+			return args[0], nil
+		}
+		switch t := args[0].Interface().(type) {
+		case rune:
+			return reflect.ValueOf(unicode.ToUpper(t)), nil
+		case string:
+			return reflect.ValueOf(strings.ToUpper(t)), nil
+		default:
+			return args[0], nil
+		}
+	})))
+
+	defineGo(env, "lower", strings.ToLower)
+
+	defineString(env, "split", 1, 2, func(args []string) (reflect.Value, error) {
+		if len(args) == 1 {
+			return stringArray(strings.Fields(args[0])), nil
+		}
+		return stringArray(strings.Split(args[0], args[1])), nil
+	})
+
+	env.DefineBuiltin("join", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 1, 2); err != nil {
+			return vm.NIL, err
+		}
+		arr, ok := args[0].Interface().([]reflect.Value)
+		if !ok {
+			return vm.NIL, fmt.Errorf("%s is not array", vm.Inspect(args[0]))
+		}
+		sep := ""
+		if len(args) == 2 {
+			s, err := getString(args[1])
+			if err != nil {
+				return vm.NIL, err
+			}
+			sep = s
+		}
+		strs := make([]string, len(arr))
+		for i, v := range arr {
+			strs[i] = vm.ToString(v)
+		}
+		return reflect.ValueOf(strings.Join(strs, sep)), nil
+	})))
+
+	defineString(env, "trim", 1, 2, func(args []string) (reflect.Value, error) {
+		if len(args) == 1 {
+			return reflect.ValueOf(strings.TrimSpace(args[0])), nil
+		}
+		return reflect.ValueOf(strings.Trim(args[0], args[1])), nil
+	})
+
+	defineGo(env, "replace", func(s, old, new string) string {
+		return strings.Replace(s, old, new, -1)
+	})
+
+	defineGo(env, "startswith", strings.HasPrefix)
+
+	defineGo(env, "endswith", strings.HasSuffix)
+
+	env.DefineBuiltin("contains", helper(func(a reflect.Value, b reflect.Value) (reflect.Value, error) {
+		if !a.IsValid() {
+			return vm.NIL, fmt.Errorf("nil is not string, array or map")
+		}
+		switch t := a.Interface().(type) {
+		case string:
+			sub, err := getString(b)
+			if err != nil {
+				return vm.NIL, err
+			}
+			return reflect.ValueOf(strings.Contains(t, sub)), nil
+		case []reflect.Value:
+			for _, v := range t {
+				if vm.Equal(v, b) {
+					return reflect.ValueOf(true), nil
+				}
+			}
+			return reflect.ValueOf(false), nil
+		case *vm.Map:
+			_, ok := t.Get(b)
+			return reflect.ValueOf(ok), nil
+		}
+		return vm.NIL, fmt.Errorf("%s is not string, array or map", vm.Inspect(a))
+	}))
+
+	env.DefineBuiltin("len", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return vm.NIL, err
+		}
+		if args[0].IsValid() {
+			switch t := args[0].Interface().(type) {
+			case string:
+				return reflect.ValueOf(vm.NewInt(int64(len([]rune(t))))), nil
+			case []reflect.Value:
+				return reflect.ValueOf(vm.NewInt(int64(len(t)))), nil
+			case *vm.Map:
+				return reflect.ValueOf(vm.NewInt(int64(t.Len()))), nil
+			}
+		}
+		return vm.NIL, fmt.Errorf("%s has no length", vm.Inspect(args[0]))
+	})))
+
+	env.DefineBuiltin("substr", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 2, 3); err != nil {
+			return vm.NIL, err
+		}
+		s, err := getString(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		runes := []rune(s)
+		start, ok := vm.GetInt(args[1])
+		if !ok || start < 0 || start > int64(len(runes)) {
+			return vm.NIL, fmt.Errorf("invalid start %s. length is %d", vm.Inspect(args[1]), len(runes))
+		}
+		end := int64(len(runes))
+		if len(args) == 3 {
+			length, ok := vm.GetInt(args[2])
+			if !ok || length < 0 {
+				return vm.NIL, fmt.Errorf("invalid length %s", vm.Inspect(args[2]))
+			}
+			if start+length < end {
+				end = start + length
+			}
+		}
+		return reflect.ValueOf(string(runes[start:end])), nil
+	})))
+
+	env.DefineBuiltin("match", helper(func(a reflect.Value, b reflect.Value) (reflect.Value, error) {
+		s, err := getString(a)
+		if err != nil {
+			return vm.NIL, err
+		}
+		re, err := getRegexp(b)
+		if err != nil {
+			return vm.NIL, err
+		}
+		if m := re.FindStringSubmatch(s); m != nil {
+			return stringArray(m), nil
+		}
+		return vm.NIL, nil
+	}))
+
+	env.DefineBuiltin("gsub", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 3, 3); err != nil {
+			return vm.NIL, err
+		}
+		s, err := getString(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		re, err := getRegexp(args[1])
+		if err != nil {
+			return vm.NIL, err
+		}
+		repl, err := getString(args[2])
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(re.ReplaceAllString(s, repl)), nil
+	})))
+
+	env.DefineBuiltin("str", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(vm.ToString(args[0])), nil
+	})))
+
+	env.DefineBuiltin("num", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if err := checkArgs(args, 1, 1); err != nil {
+			return vm.NIL, err
+		}
+		if args[0].IsValid() {
+			if n, ok := args[0].Interface().(vm.Number); ok {
+				return reflect.ValueOf(n), nil
+			}
+		}
+		s, err := getString(args[0])
+		if err != nil {
+			return vm.NIL, err
+		}
+		if n, ok := vm.SscanNumber(strings.TrimSpace(s)); ok {
+			return reflect.ValueOf(n), nil
+		}
+		return vm.NIL, fmt.Errorf("%s is not number", vm.Inspect(args[0]))
+	})))
+}
@@ -0,0 +1,213 @@
+package builtins
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"../pipe"
+	"../vm"
+)
+
+//getDuration converts args[0] as milliseconds
+func getDuration(args []reflect.Value) (time.Duration, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of argments")
+	}
+	ms, ok := vm.GetInt(args[0])
+	if !ok || ms < 0 {
+		return 0, fmt.Errorf("milliseconds must be non-negative integer. got %s", vm.Inspect(args[0]))
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+//getRate converts args[0] to interval between values. args[0] is number of values per second
+func getRate(args []reflect.Value) (time.Duration, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("wrong number of argments")
+	}
+	if args[0].IsValid() {
+		if n, ok := args[0].Interface().(vm.Number); ok && n.Sign() > 0 {
+			rate, _ := n.Float64()
+			return time.Duration(float64(time.Second) / rate), nil
+		}
+	}
+	return 0, fmt.Errorf("rate must be positive number. got %s", vm.Inspect(args[0]))
+}
+
+//unixMilli returns t as milliseconds since 1970-01-01 UTC
+func unixMilli(t time.Time) reflect.Value {
+	return reflect.ValueOf(vm.NewInt(t.UnixNano() / int64(time.Millisecond)))
+}
+
+//LoadTime defines time function
+func LoadTime(env *vm.Env) {
+	env.DefineBuiltin("now", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 0 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		return unixMilli(time.Now()), nil
+	})))
+
+	//interval emits 0, 1, 2, ... every ms until downstream stops reading
+	env.DefineBuiltin("interval", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		d, err := getDuration(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		if d == 0 {
+			return vm.NIL, fmt.Errorf("interval must be positive")
+		}
+		valve := pipe.NewContextValve(env.Context())
+		go func() {
+			defer valve.Close()
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			for i := int64(0); ; i++ {
+				<-ticker.C
+				if !valve.Send(reflect.ValueOf(vm.NewInt(i))) {
+					return
+				}
+			}
+		}()
+		return reflect.ValueOf(pipe.NewProducer(valve)), nil
+	})))
+
+	//after emits current time once after ms
+	env.DefineBuiltin("after", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		d, err := getDuration(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		valve := pipe.NewContextValve(env.Context())
+		go func() {
+			defer valve.Close()
+			valve.Send(unixMilli(<-time.After(d)))
+		}()
+		return reflect.ValueOf(pipe.NewProducer(valve)), nil
+	})))
+
+	//timeout closes stream when no value arrives within ms
+	env.DefineBuiltin("timeout", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		d, err := getDuration(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+			for {
+				select {
+				case v, ok := <-r:
+					if !ok || !w.Send(v) {
+						return
+					}
+					if !timer.Stop() {
+						<-timer.C
+					}
+					timer.Reset(d)
+				case <-timer.C:
+					return
+				}
+			}
+		})), nil
+	})))
+
+	//throttle delays values so that at most n values per second pass.
+	//while it waits upstream is blocked, so nothing is dropped
+	env.DefineBuiltin("throttle", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		gap, err := getRate(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			next := time.Now()
+			for v := range r {
+				if d := time.Until(next); d > 0 {
+					time.Sleep(d)
+				} else {
+					next = time.Now()
+				}
+				if !w.Send(v) {
+					return
+				}
+				next = next.Add(gap)
+			}
+		})), nil
+	})))
+
+	//debounce emits a value only when no newer value arrives within ms.
+	//pending value is emitted when stream ends
+	env.DefineBuiltin("debounce", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		d, err := getDuration(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			var pending reflect.Value
+			timer := time.NewTimer(d)
+			timer.Stop()
+			defer timer.Stop()
+			for {
+				select {
+				case v, ok := <-r:
+					if !ok {
+						if pending.IsValid() {
+							w.Send(pending)
+						}
+						return
+					}
+					if !timer.Stop() {
+						select {
+						case <-timer.C:
+						default:
+						}
+					}
+					pending = v
+					timer.Reset(d)
+				case <-timer.C:
+					if !w.Send(pending) {
+						return
+					}
+					pending = pipe.EOF
+				}
+			}
+		})), nil
+	})))
+
+	//sample emits the latest value every ms if a new value arrived.
+	//pending value is emitted when stream ends
+	env.DefineBuiltin("sample", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		d, err := getDuration(args)
+		if err != nil {
+			return vm.NIL, err
+		}
+		if d == 0 {
+			return vm.NIL, fmt.Errorf("interval must be positive")
+		}
+		return reflect.ValueOf(pipe.NewFilter(func(r <-chan reflect.Value, w pipe.Valve) {
+			var latest reflect.Value
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			for {
+				select {
+				case v, ok := <-r:
+					if !ok {
+						if latest.IsValid() {
+							w.Send(latest)
+						}
+						return
+					}
+					latest = v
+				case <-ticker.C:
+					if latest.IsValid() {
+						if !w.Send(latest) {
+							return
+						}
+						latest = pipe.EOF
+					}
+				}
+			}
+		})), nil
+	})))
+}
@@ -1,9 +1,11 @@
 package builtins
 
 import (
-	"log"
+	"fmt"
 	"reflect"
 
+	"../ast"
+	"../logging"
 	"../pipe"
 	"../vm"
 )
@@ -11,7 +13,7 @@ import (
 //LoadUtil defines utility function
 func LoadUtil(env *vm.Env) {
 	env.DefineBuiltin("seq", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
-		valve := pipe.NewValve()
+		valve := pipe.NewContextValve(env.Context())
 		var start int64
 		var end int64
 		if len(args) == 1 {
@@ -21,14 +23,15 @@ func LoadUtil(env *vm.Env) {
 			start = args[0].Interface().(vm.Number).ToInt()
 			end = args[1].Interface().(vm.Number).ToInt()
 		}
+		l := env.Logger()
 		go func() {
 			defer func() {
-				log.Println("seq close")
+				l.Log(logging.Debug, "seq close", logging.F("start", start), logging.F("end", end))
 				valve.Close()
 			}()
 			for i := start; i <= end; i++ {
 				if !valve.Send(reflect.ValueOf(vm.NewInt(int64(i)))) {
-					log.Println("seq Done")
+					l.Log(logging.Debug, "seq done", logging.F("value", i))
 					return
 				}
 			}
@@ -36,15 +39,60 @@ func LoadUtil(env *vm.Env) {
 		return reflect.ValueOf(pipe.NewProducer(valve)), nil
 	})))
 
-	env.DefineBuiltin("chan", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
-		return reflect.ValueOf(pipe.NewChan()), nil
+	env.DefineBuiltin("recover", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
+		if len(args) != 2 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		fs := make([]vm.Function, len(args))
+		for i, arg := range args {
+			var ok bool
+			if arg.IsValid() {
+				fs[i], ok = arg.Interface().(vm.Function)
+			}
+			if !ok {
+				return vm.NIL, fmt.Errorf("%s is not function", vm.Inspect(arg))
+			}
+		}
+		return reflect.ValueOf(vm.NewRecoverFunction(fs[0], fs[1])), nil
+	})))
+
+	env.DefineBuiltin("chan", reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		if len(args) == 0 {
+			return reflect.ValueOf(pipe.NewChan()), nil
+		}
+		if len(args) > 2 {
+			return vm.NIL, fmt.Errorf("wrong number of argments")
+		}
+		capacity, ok := vm.GetInt(args[0])
+		if !ok || capacity < 0 {
+			return vm.NIL, fmt.Errorf("capacity must be non-negative integer. got %s", vm.Inspect(args[0]))
+		}
+		policy := pipe.Block
+		if len(args) == 2 {
+			name, err := getString(args[1])
+			if err != nil {
+				return vm.NIL, err
+			}
+			if policy, err = pipe.ParsePolicy(name); err != nil {
+				return vm.NIL, err
+			}
+			if capacity == 0 && policy != pipe.Block {
+				return vm.NIL, fmt.Errorf("policy %s needs capacity", name)
+			}
+		}
+		overflow := func() {
+			env.ReportError(vm.Errorf(pos, "chan overflow. capacity is %d", capacity))
+		}
+		return reflect.ValueOf(pipe.NewBufferedChan(int(capacity), policy, overflow)), nil
 	})))
 
 	env.DefineBuiltin("last", reflect.ValueOf(vm.NewBuiltinFunction(func(args ...reflect.Value) (reflect.Value, error) {
-		fun := func(r <-chan reflect.Value) reflect.Value {
-			defer log.Println("last end")
-			log.Println("last start")
-			var ret reflect.Value
+		l := env.Logger()
+		fun := func(r <-chan reflect.Value) (ret reflect.Value) {
+			l.Log(logging.Debug, "last start")
+			defer func() {
+				l.Log(logging.Debug, "last end", logging.F("result", ret))
+			}()
 			if len(args) == 1 {
 				ret = args[0]
 			}
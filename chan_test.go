@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./vm"
+)
+
+//slow is consumer side of chan in tests. it's slower than seq so that chan overflows
+const slow = `{x -> i = 0; while i < 1000 {i = i + 1}; x}`
+
+func TestChanPolicy(t *testing.T) {
+	//the newest values are dropped while the buffer is full. the first one is kept
+	assertNum(`xs = seq(100) | chan(2,"drop_newest") | `+slow+` | collect();if len(xs) < 100 && xs[0] == 1 {1} else {0}`, "1", t)
+	//the oldest values are dropped. the last one is kept
+	assertNum(`xs = seq(100) | chan(2,"drop_oldest") | `+slow+` | collect();if len(xs) < 100 && xs[len(xs) - 1] == 100 {1} else {0}`, "1", t)
+	assertNum(`xs = seq(100) | chan(2,"block") | `+slow+` | collect();len(xs)`, "100", t)
+}
+
+func TestChanOverflow(t *testing.T) {
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	defer func() {
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}()
+	_, err := eval(`c = chan(2,"error"); seq(100) | c; c | `+slow+` | collect()`, env)
+	if err == nil || !strings.Contains(err.Error(), "line: 1, Column: 4\nchan(2,\"error\")\nError: chan overflow. capacity is 2") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestChanArgs(t *testing.T) {
+	assertError(`chan(0-1)`, "capacity must be non-negative integer. got -1", t)
+	assertError(`chan(0,"drop_oldest")`, "policy drop_oldest needs capacity", t)
+	assertError(`chan(2,"drop")`, "unknown policy drop", t)
+}
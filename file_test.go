@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./vm"
+)
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nstrm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	env.Define("path", reflect.ValueOf(filepath.Join(dir, "out.txt")))
+	env.Define("missing", reflect.ValueOf(filepath.Join(dir, "missing.txt")))
+
+	steps := []struct{ src, expected string }{
+		{`["a","b"] | fwrite(path)`, ""},
+		{`[1] | fappend(path)`, ""},
+		{`fread(path) | collect()`, `["a", "b", "1"]`},
+	}
+	for _, s := range steps {
+		if got, err := eval(s.src, env); err != nil {
+			t.Fatal(err)
+		} else if got != s.expected {
+			t.Errorf("%s got %q expected %q", s.src, got, s.expected)
+		}
+	}
+	if _, err := eval(`fread(missing)`, env); err == nil {
+		t.Errorf("fread of missing file must be error")
+	}
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+}
+
+func TestFileError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nstrm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "out.txt")
+	if err := ioutil.WriteFile(path, []byte("a\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	env.Define("path", reflect.ValueOf(path))
+	env.Define("dir", reflect.ValueOf(dir))
+
+	//file is not truncated until the pipe runs
+	if _, err := eval(`w = fwrite(path)`, env); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := eval(`fread(path) | collect()`, env); err != nil {
+		t.Fatal(err)
+	} else if got != `["a"]` {
+		t.Errorf("file is changed before writing. got %q", got)
+	}
+	//dir can't be written or read as file. the error points the call
+	for _, s := range []struct{ src, expected string }{
+		{`[1] | fwrite(dir)`, "Column: 6"},
+		{`fread(dir) | collect()`, "Column: 0"},
+	} {
+		if _, err := eval(s.src, env); err == nil {
+			t.Errorf("%s must be error", s.src)
+		} else if !strings.Contains(err.Error(), s.expected) {
+			t.Errorf("%s got %q", s.src, err)
+		}
+	}
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+}
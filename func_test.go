@@ -1,7 +1,54 @@
 package main
 
-import "testing"
+import (
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./vm"
+)
+
+//assertParts runs each of srcs in the same Env like lines of REPL. the result of the last one must be expected
+func assertParts(srcs []string, expected string, t *testing.T) {
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	defer func() {
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}()
+	got := ""
+	for _, src := range srcs {
+		var err error
+		if got, err = eval(src, env); err != nil {
+			t.Fatalf("%s: %v", src, err)
+		}
+	}
+	if got != expected {
+		t.Errorf("%v: got %s expected %s", srcs, got, expected)
+	}
+}
 
 func TestBlock(t *testing.T) {
 	assertNum("a={x->x};a(10)", "10", t)
 }
+
+func TestScope(t *testing.T) {
+	//assignment in block updates variable of outer block
+	assertNum("counter={->n=0;{->n=n+1}};c=counter();c();c();c()", "3", t)
+	//formal argment shadows outer variable
+	assertNum("x=1;f={x->x*2};f(5)+x", "11", t)
+	//top level variable is updated from block even if it's defined later
+	assertNum("f={->y=10};y=1;f();y", "10", t)
+	assertParts([]string{"f={->y=10}", "y=1", "f()", "y"}, "10", t)
+	//block updates top level variable even if it was local when the block was called before
+	assertParts([]string{"f={->z=10;z}", "f()", "z=1", "f()", "z"}, "10", t)
+	assertParts([]string{"counter={->n=0;{->n=n+1}}", "c=counter()", "c();c();c()"}, "3", t)
+	//block can refer variable defined by later part
+	assertParts([]string{"g={->z}", "z=5;g()"}, "5", t)
+	//variable bound in while body is local to each iteration
+	assertNum("f={n->i=0;s=0;while i<n {t=i;s=s+t;i=i+1};s};f(5)", "10", t)
+	//recursion refers itself by top level name
+	assertNum("fib={n->if n<2 {n}else{fib(n-1)+fib(n-2)}};fib(10)", "55", t)
+}
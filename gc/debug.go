@@ -0,0 +1,133 @@
+package gc
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var debugflag int32
+
+//SetDebug enables debug mode. Incref and Decref record their callers so that Report can show where leaked references come from.
+//it should be called before any Ref is used.
+func SetDebug(on bool) {
+	if on {
+		atomic.StoreInt32(&debugflag, 1)
+	} else {
+		atomic.StoreInt32(&debugflag, 0)
+	}
+}
+
+func debug() bool {
+	return atomic.LoadInt32(&debugflag) == 1
+}
+
+//trace is history of refcount recorded in debug mode
+type trace struct {
+	created string
+	sites   map[string]int
+	over    int
+}
+
+//registry has Refs which are alive or over-released
+var registry = struct {
+	sync.Mutex
+	live map[*Ref]*trace
+	over map[*Ref]*trace
+}{
+	live: make(map[*Ref]*trace),
+	over: make(map[*Ref]*trace),
+}
+
+var pkgpath = reflect.TypeOf(trace{}).PkgPath()
+
+//internal returns true if function is method of Ref, helper such as Incif, or Incref and Decref which wrap Ref
+func internal(function string) bool {
+	if strings.HasSuffix(function, ").Incref") || strings.HasSuffix(function, ").Decref") {
+		return true
+	}
+	for _, name := range []string{"(*Ref).", "Incif", "Decif", "Waitif"} {
+		if strings.HasPrefix(function, pkgpath+"."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+//caller returns the first caller except for Ref and its helpers. eg "vm/value.go:97"
+func caller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !internal(frame.Function) && frame.File != "<autogenerated>" {
+			return filepath.Join(filepath.Base(filepath.Dir(frame.File)), filepath.Base(frame.File)) + fmt.Sprintf(":%d", frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+//record records Incref or Decref. n is refcount after it
+func (r *Ref) record(delta int, n int32) {
+	site := caller()
+	registry.Lock()
+	defer registry.Unlock()
+	t := r.trace
+	if t == nil {
+		t = &trace{created: site, sites: make(map[string]int)}
+		r.trace = t
+	}
+	if delta > 0 {
+		t.sites["+ "+site]++
+	} else {
+		t.sites["- "+site]++
+	}
+	switch {
+	case n > 0:
+		registry.live[r] = t
+	case n == 0:
+		delete(registry.live, r)
+	default:
+		t.over++
+		registry.over[r] = t
+	}
+}
+
+//Report writes Refs which are not released and Refs which are decremented too much in debug mode.
+//it returns the number of them. call it at exit of program.
+func Report(w io.Writer) int {
+	registry.Lock()
+	defer registry.Unlock()
+	show := func(title string, refs map[*Ref]*trace, count func(*Ref, *trace) string) {
+		if len(refs) == 0 {
+			return
+		}
+		lines := []string{}
+		for r, t := range refs {
+			sites := []string{}
+			for site, n := range t.sites {
+				sites = append(sites, fmt.Sprintf("    %s (%d times)", site, n))
+			}
+			sort.Strings(sites)
+			lines = append(lines, fmt.Sprintf("  created at %s, %s\n%s", t.created, count(r, t), strings.Join(sites, "\n")))
+		}
+		sort.Strings(lines)
+		fmt.Fprintf(w, "%s: %d\n%s\n", title, len(refs), strings.Join(lines, "\n"))
+	}
+	show("leaked references", registry.live, func(r *Ref, t *trace) string {
+		return fmt.Sprintf("refcount %d", r.Refcount())
+	})
+	show("over-released references", registry.over, func(r *Ref, t *trace) string {
+		return fmt.Sprintf("%d extra decref", t.over)
+	})
+	return len(registry.live) + len(registry.over)
+}
@@ -3,6 +3,7 @@ package gc
 import (
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 //GcThing is data that is controlled by GC
@@ -42,20 +43,99 @@ func Waitif(v reflect.Value) {
 	}
 }
 
-//Ref is refcount
-type Ref sync.WaitGroup
+//Ref is refcount. zero value is ready to use.
+//when refcount becomes zero, Ref is released and functions registered by OnRelease are called.
+type Ref struct {
+	count      int32
+	mutex      sync.Mutex
+	released   bool
+	done       chan struct{}
+	finalizers []func()
+	trace      *trace
+}
 
 //Incref increments refcount
 func (r *Ref) Incref() {
-	(*sync.WaitGroup)(r).Add(1)
+	n := atomic.AddInt32(&r.count, 1)
+	if debug() {
+		r.record(1, n)
+	}
 }
 
-//Decref decrements refcount
+//Decref decrements refcount. Ref is released when it becomes zero.
+//extra Decref is ignored and reported in debug mode.
 func (r *Ref) Decref() {
-	(*sync.WaitGroup)(r).Done()
+	for {
+		old := atomic.LoadInt32(&r.count)
+		//refcount must not go below zero even for a moment because Incref at that time would see zero
+		if old <= 0 {
+			if debug() {
+				r.record(-1, old-1)
+			}
+			return
+		}
+		if atomic.CompareAndSwapInt32(&r.count, old, old-1) {
+			if debug() {
+				r.record(-1, old-1)
+			}
+			if old == 1 {
+				r.release()
+			}
+			return
+		}
+	}
+}
+
+//Refcount returns current refcount
+func (r *Ref) Refcount() int {
+	return int(atomic.LoadInt32(&r.count))
+}
+
+//OnRelease registers f to be called when Ref is released. f is called immediately if it's already released.
+//f is called in the goroutine which calls the last Decref, so it must not block.
+func (r *Ref) OnRelease(f func()) {
+	r.mutex.Lock()
+	if r.released {
+		r.mutex.Unlock()
+		f()
+		return
+	}
+	r.finalizers = append(r.finalizers, f)
+	r.mutex.Unlock()
+}
+
+func (r *Ref) release() {
+	r.mutex.Lock()
+	if r.released {
+		r.mutex.Unlock()
+		return
+	}
+	r.released = true
+	finalizers := r.finalizers
+	r.finalizers = nil
+	if r.done != nil {
+		close(r.done)
+	}
+	r.mutex.Unlock()
+	for _, f := range finalizers {
+		f()
+	}
 }
 
 //Wait wait untill refcount is zero
 func (r *Ref) Wait() {
-	(*sync.WaitGroup)(r).Wait()
+	if atomic.LoadInt32(&r.count) <= 0 {
+		return
+	}
+	r.mutex.Lock()
+	if r.released {
+		r.mutex.Unlock()
+		return
+	}
+	if r.done == nil {
+		r.done = make(chan struct{})
+	}
+	done := r.done
+	r.mutex.Unlock()
+	<-done
 }
@@ -1,6 +1,9 @@
 package gc
 
 import (
+	"bytes"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -22,3 +25,89 @@ func TestSimpleRefCountParallel(t *testing.T) {
 	go a.Decref()
 	a.Wait()
 }
+
+func TestExtraDecref(t *testing.T) {
+	a := A{a: 10}
+	a.Incref()
+	a.Decref()
+	a.Decref()
+	if n := a.Refcount(); n != 0 {
+		t.Errorf("extra decref must be ignored. got %d", n)
+	}
+}
+
+func TestExtraDecrefParallel(t *testing.T) {
+	a := A{a: 10}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10000; j++ {
+				a.Decref()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10000; j++ {
+				a.Incref()
+				a.Decref()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := a.Refcount(); n != 0 {
+		t.Errorf("extra decref must not change refcount held by others. got %d", n)
+	}
+}
+
+func TestOnRelease(t *testing.T) {
+	a := A{a: 10}
+	released := 0
+	a.OnRelease(func() {
+		released++
+	})
+	a.Incref()
+	a.Incref()
+	a.Decref()
+	if released != 0 {
+		t.Errorf("released before refcount is zero")
+	}
+	a.Decref()
+	if released != 1 {
+		t.Errorf("finalizer must be called once. got %d", released)
+	}
+	a.OnRelease(func() {
+		released++
+	})
+	if released != 2 {
+		t.Errorf("finalizer must be called immediately after release")
+	}
+}
+
+func TestReport(t *testing.T) {
+	SetDebug(true)
+	defer SetDebug(false)
+	registry.Lock()
+	registry.live = make(map[*Ref]*trace)
+	registry.over = make(map[*Ref]*trace)
+	registry.Unlock()
+	leaked := &A{}
+	leaked.Incref()
+	leaked.Incref()
+	leaked.Decref()
+	over := &A{}
+	over.Incref()
+	over.Decref()
+	over.Decref()
+	var buf bytes.Buffer
+	if n := Report(&buf); n != 2 {
+		t.Errorf("expected 2 problems. got %d\n%s", n, buf.String())
+	}
+	for _, expected := range []string{"leaked references: 1", "refcount 1", "over-released references: 1", "1 extra decref", "+ gc/gc_test.go:"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("%q is not in report\n%s", expected, buf.String())
+		}
+	}
+	leaked.Decref()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./importer"
+	"./vm"
+)
+
+func TestImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nstrm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"lib/util.nstrm": "twice = {x -> x * 2}\nbase = 10",
+		"a.nstrm":        `import "b.nstrm"`,
+		"b.nstrm":        `import "a.nstrm"`,
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	loader := importer.NewLoader([]string{filepath.Join(dir, "lib")}, builtins.LoadCore)
+	loader.Install(env, filepath.Join(dir, "main.nstrm"))
+
+	steps := []struct{ src, expected string }{
+		{`import "util.nstrm" as u;u.twice(u.base)`, "20"},
+		{`import "lib/util.nstrm";twice(3)`, "6"},
+	}
+	for _, s := range steps {
+		if got, err := eval(s.src, env); err != nil {
+			t.Fatal(err)
+		} else if got != s.expected {
+			t.Errorf("%s got %q expected %q", s.src, got, s.expected)
+		}
+	}
+	if _, err := eval(`import "a.nstrm"`, env); err == nil || !strings.Contains(err.Error(), "import cycle") {
+		t.Errorf("import cycle must be error. got %v", err)
+	}
+	if _, err := eval(`import "missing.nstrm"`, env); err == nil {
+		t.Errorf("import of missing file must be error")
+	}
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+	loader.Close()
+}
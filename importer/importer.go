@@ -0,0 +1,188 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"../logging"
+	"../parser"
+	"../vm"
+)
+
+//module is a file loaded by Loader
+type module struct {
+	env      *vm.Env
+	bindings map[string]reflect.Value
+	err      error
+	done     chan bool
+}
+
+//Loader loads files for import expression. each file is evaluated once in its own Env
+type Loader struct {
+	path    []string
+	setup   func(*vm.Env)
+	modules map[string]*module
+	mutex   sync.Mutex
+	wg      sync.WaitGroup
+}
+
+//NewLoader creates Loader. path is list of directories searched after the directory of importing file.
+//setup is called with Env of each module to define builtin functions.
+func NewLoader(path []string, setup func(*vm.Env)) *Loader {
+	return &Loader{
+		path:    path,
+		setup:   setup,
+		modules: make(map[string]*module),
+	}
+}
+
+//SplitPath splits search path joined with os.PathListSeparator. eg "lib:/usr/share/nstrm"
+func SplitPath(s string) []string {
+	ret := []string{}
+	for _, dir := range filepath.SplitList(s) {
+		if dir != "" {
+			ret = append(ret, dir)
+		}
+	}
+	return ret
+}
+
+//Install enables import expression in env. file is the source file of env, or "" if there is no file.
+func (l *Loader) Install(env *vm.Env, file string) {
+	chain := []string{}
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+			chain = append(chain, abs)
+		}
+	}
+	env.SetImporter(l.importer(file, chain))
+}
+
+//Close releases all modules and blocks until they end
+func (l *Loader) Close() {
+	l.mutex.Lock()
+	modules := l.modules
+	l.modules = make(map[string]*module)
+	l.mutex.Unlock()
+	for _, m := range modules {
+		<-m.done
+		if m.env != nil {
+			m.env.RunWait(vm.NIL)
+			m.env.Decref()
+		}
+	}
+	l.wg.Wait()
+}
+
+//importer creates Importer for the file. chain is list of files being imported which ends with the file
+func (l *Loader) importer(file string, chain []string) vm.Importer {
+	return func(env *vm.Env, path string) (map[string]reflect.Value, error) {
+		abs, err := l.resolve(file, path)
+		if err != nil {
+			return nil, err
+		}
+		for i, f := range chain {
+			if f == abs {
+				cycle := append(append([]string{}, chain[i:]...), abs)
+				return nil, fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+			}
+		}
+		return l.load(env, abs, append(chain[:len(chain):len(chain)], abs))
+	}
+}
+
+//resolve finds path from the directory of file and search path
+func (l *Loader) resolve(file string, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return filepath.Clean(path), nil
+		}
+		return "", fmt.Errorf("%s is not found", path)
+	}
+	dir := "."
+	if file != "" {
+		dir = filepath.Dir(file)
+	}
+	dirs := append([]string{dir}, l.path...)
+	for _, d := range dirs {
+		candidate := filepath.Join(d, path)
+		if isFile(candidate) {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("%s is not found in %s", path, strings.Join(dirs, ", "))
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+//load returns bindings of module at abs. it evaluates the file at first time
+func (l *Loader) load(env *vm.Env, abs string, chain []string) (map[string]reflect.Value, error) {
+	l.mutex.Lock()
+	if m, ok := l.modules[abs]; ok {
+		l.mutex.Unlock()
+		<-m.done
+		return m.bindings, m.err
+	}
+	m := &module{done: make(chan bool)}
+	l.modules[abs] = m
+	l.mutex.Unlock()
+
+	m.env, m.bindings, m.err = l.eval(env, abs, chain)
+	close(m.done)
+	return m.bindings, m.err
+}
+
+//eval evaluates file in new Env and returns variables defined by it
+func (l *Loader) eval(importing *vm.Env, abs string, chain []string) (*vm.Env, map[string]reflect.Value, error) {
+	buffer, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return nil, nil, err
+	}
+	p := &parser.Nstrm{Buffer: string(buffer)}
+	p.Init()
+	p.MyParser.Init()
+	if err := p.Parse(); err != nil {
+		return nil, nil, fmt.Errorf("parse error in %s", abs)
+	}
+	p.Execute()
+
+	importing.Logger().Log(logging.Debug, "import", logging.F("path", abs))
+	env := vm.NewEnv(&l.wg)
+	env.Follow(importing)
+	if l.setup != nil {
+		l.setup(env)
+	}
+	builtins := env.Bindings()
+	env.SetSource(&vm.Source{Name: abs, Buffer: p.Buffer})
+	env.SetImporter(l.importer(abs, chain))
+	env.SetErrorHandler(importing.ReportError)
+
+	if _, err := p.Run(env); err != nil {
+		if E, ok := err.(*vm.Error); ok {
+			if E.Source == nil {
+				E.Source = &vm.Source{Name: abs, Buffer: p.Buffer}
+			}
+			return env, nil, E
+		}
+		return env, nil, fmt.Errorf("unexpected %T at toplevel of %s", err, abs)
+	}
+	env.Flush()
+
+	ret := make(map[string]reflect.Value)
+	for name, v := range env.Bindings() {
+		if b, ok := builtins[name]; ok && b == v {
+			continue
+		}
+		ret[name] = v
+	}
+	return env, ret, nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestIndexArray(t *testing.T) {
+	assertNum("a=[1,2,3];a[0]+a[2]", "4", t)
+}
+
+func TestIndexNested(t *testing.T) {
+	assertNum("a=[[1,2],[3,4]];a[1][0]", "3", t)
+}
+
+func TestIndexMap(t *testing.T) {
+	assertNum(`m={a: [5,6]};m["a"][1]`, "6", t)
+}
+
+func TestSlice(t *testing.T) {
+	assertNum("a=[1,2,3,4];a[1:3][1]+a[:1][0]+a[3:][0]", "8", t)
+}
+
+func TestSliceString(t *testing.T) {
+	assertNum(`if "hello"[1:3] == "el" {1} else {0}`, "1", t)
+}
+
+func TestIndexError(t *testing.T) {
+	assertError(`a=[1,2,3];a[3]`, "index 3 out of range. length is 3", t)
+	assertError(`a=[1,2,3];a[0-1]`, "index -1 out of range. length is 3", t)
+	assertError(`"abc"[5]`, "index 5 out of range. length is 3", t)
+	assertError(`a=[1,2,3];a[1.5]`, "index must be integer", t)
+	assertError(`a=[1,2,3];a["x"]`, `index must be integer. got "x"`, t)
+	assertError(`a=[1,2,3];a[1:5]`, "slice [1:5] out of range. length is 3", t)
+}
+
+func TestIndexMapMissing(t *testing.T) {
+	//missing key is nil, which can't be sent to pipe
+	assertNum(`m={a: 1};if m["zz"] == nil {1} else {0}`, "1", t)
+	assertError(`m={a: 1};[m["zz"]] | STDOUT`, "can't send nil to pipe", t)
+	assertError(`[1] | {x -> emit nil} | STDOUT`, "line: 1, Column: 17\nnil\nError: can't emit nil", t)
+}
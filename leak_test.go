@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./gc"
+	"./parser"
+	"./vm"
+)
+
+//assertNoLeak runs expr with the compiler and the tree-walker in debug mode of gc, and checks that all references made by it are released
+func assertNoLeak(expr string, t *testing.T) {
+	gc.SetDebug(true)
+	defer gc.SetDebug(false)
+	runs := map[string]func(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue){
+		"Run":       func(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue) { return p.Run(env) },
+		"Interpret": func(p *parser.Nstrm, env *vm.Env) (reflect.Value, vm.SpecialValue) { return p.Interpret(env) },
+	}
+	for name, run := range runs {
+		before := gc.Report(ioutil.Discard)
+		p := &parser.Nstrm{Buffer: expr}
+		p.Init()
+		p.MyParser.Init()
+		if err := p.Parse(); err != nil {
+			t.Fatal(err)
+		}
+		p.Execute()
+		var wg sync.WaitGroup
+		env := vm.NewEnv(&wg)
+		builtins.LoadCore(env)
+		v, _ := run(p, env)
+		env.RunWait(v)
+		env.Decref()
+		wg.Wait()
+		var buf bytes.Buffer
+		if n := gc.Report(&buf); n != before {
+			t.Errorf("%s: %s leaks %d references\n%s", name, expr, n-before, buf.String())
+		}
+	}
+}
+
+func TestWhileNoLeak(t *testing.T) {
+	assertNoLeak(`i=0; while i < 3 {i = i + 1}`, t)
+	assertNoLeak(`i=0; while [i][5] {i = i + 1}`, t)
+}
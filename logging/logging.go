@@ -0,0 +1,181 @@
+//Package logging provides leveled logger with structured fields which is used by interpreter and pipes.
+//
+//	l := logging.New(os.Stderr, logging.Debug, false)
+//	l.Log(logging.Debug, "sent", logging.F("pipe", 3), logging.F("value", v))
+//
+//Logger is an interface so that embedding application can pass its own.
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+//Level is severity of log
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+	//Off disables all logs
+	Off
+)
+
+var levelnames = map[Level]string{
+	Debug: "debug",
+	Info:  "info",
+	Warn:  "warn",
+	Error: "error",
+	Off:   "off",
+}
+
+func (l Level) String() string {
+	return levelnames[l]
+}
+
+//ParseLevel converts name of level. eg "debug"
+func ParseLevel(name string) (Level, error) {
+	for l, n := range levelnames {
+		if n == strings.ToLower(name) {
+			return l, nil
+		}
+	}
+	return Off, fmt.Errorf("unknown log level %s. it must be debug, info, warn, error or off", name)
+}
+
+//Field is key and value attached to log
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+//F creates Field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+//Logger writes logs. it must be safe for concurrent use
+type Logger interface {
+	//Log writes msg with fields if level is enabled
+	Log(level Level, msg string, fields ...Field)
+	//With returns Logger which adds fields to every log
+	With(fields ...Field) Logger
+}
+
+type discard struct{}
+
+func (discard) Log(Level, string, ...Field) {}
+
+func (d discard) With(...Field) Logger {
+	return d
+}
+
+//Discard is Logger which writes nothing
+var Discard Logger = discard{}
+
+//writer is shared by loggers created by With
+type writer struct {
+	w     io.Writer
+	json  bool
+	mutex sync.Mutex
+}
+
+type logger struct {
+	out    *writer
+	level  Level
+	fields []Field
+}
+
+//New creates Logger which writes logs of level or higher to w, one log for each line.
+//logs are written as JSON objects if json is true, otherwise as text like `2006/01/02 15:04:05 debug sent pipe=3 value=1`
+func New(w io.Writer, level Level, json bool) Logger {
+	return &logger{out: &writer{w: w, json: json}, level: level}
+}
+
+func (l *logger) With(fields ...Field) Logger {
+	return &logger{
+		out:    l.out,
+		level:  l.level,
+		fields: append(append([]Field{}, l.fields...), fields...),
+	}
+}
+
+func (l *logger) Log(level Level, msg string, fields ...Field) {
+	if level < l.level || level >= Off {
+		return
+	}
+	all := append(append([]Field{}, l.fields...), fields...)
+	var buf bytes.Buffer
+	now := time.Now()
+	if l.out.json {
+		buf.WriteString(`{"time":`)
+		writeJSON(&buf, now.Format(time.RFC3339Nano))
+		buf.WriteString(`,"level":`)
+		writeJSON(&buf, level.String())
+		buf.WriteString(`,"msg":`)
+		writeJSON(&buf, msg)
+		for _, f := range all {
+			buf.WriteByte(',')
+			writeJSON(&buf, f.Key)
+			buf.WriteByte(':')
+			writeJSON(&buf, jsonValue(f.Value))
+		}
+		buf.WriteString("}\n")
+	} else {
+		fmt.Fprintf(&buf, "%s %s %s", now.Format("2006/01/02 15:04:05"), level, msg)
+		for _, f := range all {
+			fmt.Fprintf(&buf, " %s=%s", f.Key, textValue(f.Value))
+		}
+		buf.WriteByte('\n')
+	}
+	l.out.mutex.Lock()
+	defer l.out.mutex.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+//plain unwraps reflect.Value which is used for values in stream
+func plain(v interface{}) interface{} {
+	if r, ok := v.(reflect.Value); ok {
+		if !r.IsValid() || !r.CanInterface() {
+			return nil
+		}
+		return r.Interface()
+	}
+	return v
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch t := plain(v).(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return t
+	case error:
+		return t.Error()
+	default:
+		return fmt.Sprint(t)
+	}
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	if b, err := json.Marshal(v); err == nil {
+		buf.Write(b)
+	} else {
+		b, _ := json.Marshal(fmt.Sprint(v))
+		buf.Write(b)
+	}
+}
+
+func textValue(v interface{}) string {
+	s := fmt.Sprint(plain(v))
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
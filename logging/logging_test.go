@@ -0,0 +1,53 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{Debug, Info, Warn, Error, Off} {
+		if parsed, err := ParseLevel(l.String()); err != nil || parsed != l {
+			t.Errorf("ParseLevel(%q) got %v %v", l.String(), parsed, err)
+		}
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("unknown level must be error")
+	}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Info, false).With(F("pipe", 3))
+	l.Log(Debug, "hidden")
+	l.Log(Info, "sent", F("value", reflect.ValueOf("a b")), F("n", 1))
+	got := buf.String()
+	if strings.Contains(got, "hidden") {
+		t.Errorf("debug log must be filtered\n%s", got)
+	}
+	if !strings.HasSuffix(got, ` info sent pipe=3 value="a b" n=1`+"\n") {
+		t.Errorf("unexpected log %q", got)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Debug, true)
+	l.With(F("stage", "filter")).Log(Warn, "eof", F("value", reflect.ValueOf(2)), F("result", reflect.Value{}))
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %s", buf.String(), err)
+	}
+	expected := map[string]interface{}{"level": "warn", "msg": "eof", "stage": "filter", "value": 2.0, "result": nil}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("%s: got %v expected %v", k, got[k], v)
+		}
+	}
+	if _, ok := got["time"]; !ok {
+		t.Errorf("time is missing in %q", buf.String())
+	}
+}
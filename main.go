@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"os"
+	"os/signal"
 	"runtime"
 	"sync"
+	"syscall"
+	"time"
 
 	"./builtins"
+	"./gc"
+	"./importer"
+	"./logging"
 	"./parser"
+	"./pipe"
 	"./vm"
 
 	//	"github.com/k0kubun/pp"
@@ -18,10 +26,15 @@ import (
 func main() {
 	e := flag.String("e", "", "evaluate line")
 	v := flag.Bool("v", false, "print version")
-	d := flag.Bool("d", false, "debug mode")
+	d := flag.Bool("d", false, "debug mode. same as -log-level debug")
+	loglevel := flag.String("log-level", "warn", "level of logs written to stderr. debug, info, warn, error or off")
+	logjson := flag.Bool("log-json", false, "write logs as JSON lines")
 	numprocs := flag.Int("p", 0, "number of processes")
+	searchpath := flag.String("I", "", "search path for import. directories are separated by "+string(os.PathListSeparator))
+	timeout := flag.Duration("timeout", 0, "stop the program after the duration. eg 10s")
+	leaks := flag.Bool("leaks", false, "record refcount operations and report leaked references at exit")
+	diag := flag.Bool("diag", false, "print live pipes and blocked goroutines at exit or on the first SIGQUIT. the second SIGQUIT quits as usual")
 
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
 	flag.Parse()
 
 	if *numprocs != 0 {
@@ -33,21 +46,64 @@ func main() {
 		return
 	}
 
-	if flag.NArg() == 0 {
-		fmt.Println("no input file")
-		return
+	level, err := logging.ParseLevel(*loglevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *d {
+		level = logging.Debug
+	}
+	logger := logging.New(os.Stderr, level, *logjson)
+	if *leaks {
+		gc.SetDebug(true)
+	}
+	if *diag {
+		diagnose()
+	}
+	report := func() {
+		if *diag {
+			pipe.Dump(os.Stderr)
+		}
+		if *leaks {
+			gc.Report(os.Stderr)
+		}
 	}
 
+	loader := importer.NewLoader(importer.SplitPath(*searchpath), func(env *vm.Env) {
+		builtins.LoadCore(env)
+		builtins.LoadNet(env)
+	})
+
 	expression := ""
+	fname := ""
 	if *e != "" {
 		expression = *e
+	} else if flag.NArg() == 0 {
+		//lines for STDIN would be taken from REPL
+		builtins.Stdin = nil
+		var wg sync.WaitGroup
+		env := vm.NewEnv(&wg)
+		env.SetLogger(logger)
+		builtins.LoadCore(env)
+		builtins.LoadNet(env)
+		loader.Install(env, "")
+		h := openHistory()
+		repl(env, os.Stdin, os.Stdout, h)
+		h.close()
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+		loader.Close()
+		report()
+		return
 	} else {
-		fname := flag.Args()
-		if buffer, err := ioutil.ReadFile(fname[0]); err == nil {
+		fname = flag.Arg(0)
+		if buffer, err := ioutil.ReadFile(fname); err == nil {
 			expression = string(buffer)
 		} else {
-			log.Fatal(err)
-			return
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 	}
 	p := &parser.Nstrm{Buffer: expression}
@@ -59,20 +115,82 @@ func main() {
 	}
 	p.Execute()
 
-	if !(*d) {
-		log.SetOutput(ioutil.Discard)
+	//ctx is cancelled when a pipe fails so that the others stop
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
 
 	var wg sync.WaitGroup
 	env := vm.NewEnv(&wg)
+	env.SetContext(ctx)
+	env.SetLogger(logger)
 	builtins.LoadCore(env)
 	builtins.LoadNet(env)
+	loader.Install(env, fname)
+	if fname != "" {
+		env.SetSource(&vm.Source{Name: fname, Buffer: p.Buffer})
+	}
+	if errs := p.Check(env); len(errs) > 0 {
+		for _, E := range errs {
+			fmt.Fprintln(os.Stderr, E.Show(p.Buffer))
+		}
+		os.Exit(1)
+	}
+	var pipeerr *vm.Error
+	var pipeerrmutex sync.Mutex
+	env.SetErrorHandler(func(E *vm.Error) {
+		pipeerrmutex.Lock()
+		defer pipeerrmutex.Unlock()
+		if pipeerr == nil {
+			//it's shown after pipes are stopped
+			pipeerr = E
+			stop()
+		}
+	})
+
+	//release releases env and waits for pipes until ctx is done
+	release := func() {
+		done := make(chan bool)
+		go func() {
+			env.RunWait(vm.NIL)
+			env.Decref()
+			wg.Wait()
+			loader.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			//producers are stopped. give pipes a moment to flush
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+			}
+		}
+	}
 
 	if _, err := p.Run(env); err == nil {
-		env.Decref()
-		env.RunWait(vm.NIL)
-		wg.Wait()
+		release()
+		report()
+		pipeerrmutex.Lock()
+		E := pipeerr
+		pipeerrmutex.Unlock()
+		if E != nil {
+			E.Fatal(p.Buffer)
+		}
+		if err := ctx.Err(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
 	} else {
+		//pipes are stopped and Env is released so that report shows only leaks
+		stop()
+		release()
+		report()
 		switch E := err.(type) {
 		case *vm.Error:
 			E.Fatal(p.Buffer)
@@ -80,3 +198,16 @@ func main() {
 	}
 
 }
+
+//diagnose enables diagnostics of pipes and prints them on the first SIGQUIT instead of exiting.
+//the second SIGQUIT quits so that hung program can be stopped
+func diagnose() {
+	pipe.SetDiagnostics(true)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGQUIT)
+	go func() {
+		<-quit
+		pipe.Dump(os.Stderr)
+		signal.Reset(syscall.SIGQUIT)
+	}()
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestMapGet(t *testing.T) {
+	assertNum(`m = {a: 1, "b": 2, 3: 3};get(m,"a")+get(m,"b")+get(m,3)`, "6", t)
+}
+
+func TestMapPut(t *testing.T) {
+	assertNum(`m = {a: 1};n = put(m,"a",10);get(m,"a")+get(n,"a")`, "11", t)
+}
+
+func TestMapDefault(t *testing.T) {
+	assertNum(`get({},"a",5)`, "5", t)
+}
+
+func TestMapEqual(t *testing.T) {
+	assertNum(`if {a: 1, b: [1,2]} == {b: [1,2], a: 1} {1} else {0}`, "1", t)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"./builtins"
+	"./vm"
+)
+
+//serve runs src in background. port of tcp_server is given as variable port.
+//it returns address of the server and function to stop it
+func serve(src string, t *testing.T) (string, func()) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	env.SetContext(ctx)
+	builtins.LoadCore(env)
+	builtins.LoadNet(env)
+	env.Define("port", reflect.ValueOf(vm.NewInt(int64(port))))
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		if _, err := eval(src, env); err != nil {
+			t.Error(err)
+		}
+	}()
+	return fmt.Sprintf("127.0.0.1:%d", port), func() {
+		cancel()
+		<-done
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}
+}
+
+//talk sends request to addr, closes write side and returns everything read until the server closes
+func talk(addr string, request string, t *testing.T) string {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 100; i++ {
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	conn.(*net.TCPConn).CloseWrite()
+	ret, err := ioutil.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("server didn't close connection: %s", err)
+	}
+	return string(ret)
+}
+
+//dial runs src which connects to server at addr. port of the server is given as variable port.
+//it retries while the server isn't listening yet and returns the result of src
+func dial(src string, addr string, t *testing.T) string {
+	_, port, _ := net.SplitHostPort(addr)
+	n, _ := vm.SscanNumber(port)
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	builtins.LoadNet(env)
+	env.Define("port", reflect.ValueOf(n))
+	defer func() {
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}()
+	for i := 0; i < 100; i++ {
+		got, err := eval(src, env)
+		if err == nil {
+			return got
+		}
+		if !strings.Contains(err.Error(), "connection refused") {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server isn't listening")
+	return ""
+}
+
+func TestTCPLines(t *testing.T) {
+	addr, stop := serve(`tcp_server(port,"lines") | take(1) | {s -> s | {l -> "got ${l}"} | s; nil}`, t)
+	defer stop()
+	if got := talk(addr, "a\nb\r\n", t); got != "got a\ngot b\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTCPChunks(t *testing.T) {
+	addr, stop := serve(`tcp_server(port,"chunks") | take(1) | {s -> s | {c -> "[${c}]"} | s; nil}`, t)
+	defer stop()
+	got := talk(addr, "abc", t)
+	if !strings.HasPrefix(got, "[") || strings.NewReplacer("[", "", "]", "").Replace(got) != "abc" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTCPClose(t *testing.T) {
+	//nothing writes to s. connection must be closed when reading is end even if s is still referenced
+	addr, stop := serve(`held = nil; tcp_server(port,"lines") | take(1) | {s -> held = s; s | {l -> l} | last(); nil}`, t)
+	defer stop()
+	if got := talk(addr, "a\n", t); got != "" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestTCPConnectLines(t *testing.T) {
+	addr, stop := serve(`tcp_server(port,"lines") | take(1) | {s -> s | {l -> "got ${l}"} | s; nil}`, t)
+	defer stop()
+	got := dial(`c = tcp_connect("127.0.0.1",port,"lines"); ["a","b"] | c; c | collect()`, addr, t)
+	if got != `["got a", "got b"]` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestTCPConnectChunks(t *testing.T) {
+	addr, stop := serve(`tcp_server(port,"chunks") | take(1) | {s -> s | {c -> "[${c}]"} | s; nil}`, t)
+	defer stop()
+	got := dial(`c = tcp_connect("127.0.0.1",port,"chunks"); ["abc"] | c; c | collect()`, addr, t)
+	if !strings.HasPrefix(got, `["[`) || strings.NewReplacer("[", "", "]", "", `", "`, "").Replace(got) != `"abc"` {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestTCPConnectRefused(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	builtins.LoadNet(env)
+	defer func() {
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}()
+	src := fmt.Sprintf(`c = tcp_connect("127.0.0.1",%d,"lines")`, port)
+	if _, err := eval(src, env); err == nil || !strings.Contains(err.Error(), "connection refused") || !strings.Contains(err.Error(), "line: 1, Column: 4") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+//Package nstrm provides API to embed nstrm interpreter into Go program.
+//
+//	in := nstrm.New()
+//	defer in.Close()
+//	in.RegisterFunction("double", func(x int) int {
+//		return x * 2
+//	})
+//	v, err := in.Eval(context.Background(), `seq(3) | {x -> double(x)} | sum()`)
+//
+//Interpreter writes no logs unless SetLogger is called.
+package nstrm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"../ast"
+	"../builtins"
+	"../importer"
+	"../logging"
+	"../parser"
+	"../pipe"
+	"../vm"
+)
+
+//ProducerFunc generates values of stream by calling send.
+//send returns false when the script stops reading, then ProducerFunc should return.
+type ProducerFunc func(args []interface{}, send func(interface{}) bool) error
+
+//ConsumerFunc receives values of stream from recv until it's closed.
+//its result is the value of the consumer in script.
+type ConsumerFunc func(args []interface{}, recv <-chan interface{}) (interface{}, error)
+
+//Error is error occurred in script
+type Error struct {
+	Err    *vm.Error
+	source string
+}
+
+func (e *Error) Error() string {
+	return e.Err.Show(e.source)
+}
+
+//Interpreter runs scripts. variables defined by Eval remain for next Eval
+type Interpreter struct {
+	env    *vm.Env
+	wg     sync.WaitGroup
+	loader *importer.Loader
+	mutex  sync.Mutex
+}
+
+//New creates Interpreter which has core builtin functions.
+//searchpath is list of directories for import.
+func New(searchpath ...string) *Interpreter {
+	in := &Interpreter{
+		loader: importer.NewLoader(searchpath, builtins.LoadCore),
+	}
+	in.env = vm.NewEnv(&in.wg)
+	builtins.LoadCore(in.env)
+	in.loader.Install(in.env, "")
+	return in
+}
+
+//Close waits until all pipes end and releases Interpreter
+func (in *Interpreter) Close() {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	in.env.RunWait(vm.NIL)
+	in.env.Decref()
+	in.wg.Wait()
+	in.loader.Close()
+}
+
+//Eval runs src and returns the value of the last expression converted by vm.ToGo.
+//it blocks until pipes started by src end. error occurred in pipe is also returned.
+//ctx is also passed to the script. when it's done, producers stop and Eval returns ctx.Err() once the script has stopped.
+func (in *Interpreter) Eval(ctx context.Context, src string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	p := &parser.Nstrm{Buffer: src}
+	p.Init()
+	p.MyParser.Init()
+	if err := p.Parse(); err != nil {
+		return nil, err
+	}
+	p.Execute()
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+	var pipeerr *vm.Error
+	var pipeerrmutex sync.Mutex
+	in.env.SetContext(ctx)
+	in.env.SetErrorHandler(func(E *vm.Error) {
+		pipeerrmutex.Lock()
+		defer pipeerrmutex.Unlock()
+		if pipeerr == nil {
+			pipeerr = E
+		}
+	})
+	ret, err := p.RunPart(in.env)
+	in.env.Flush()
+	if ctxerr := ctx.Err(); ctxerr != nil {
+		return nil, ctxerr
+	}
+	if err == nil && pipeerr != nil {
+		err = pipeerr
+	}
+	switch E := err.(type) {
+	case nil:
+		return vm.ToGo(ret), nil
+	case *vm.Error:
+		return nil, &Error{Err: E, source: src}
+	default:
+		return nil, fmt.Errorf("unexpected %T at toplevel", E)
+	}
+}
+
+//SetLogger sets Logger for scripts and pipes run by Interpreter. eg logging.New(os.Stderr, logging.Debug, false)
+func (in *Interpreter) SetLogger(l logging.Logger) {
+	in.env.SetLogger(l)
+}
+
+//Get returns value of variable converted by vm.ToGo
+func (in *Interpreter) Get(name string) (interface{}, bool) {
+	v, ok := in.env.Lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return vm.ToGo(v), true
+}
+
+//Set defines variable. value is converted by vm.FromGo
+func (in *Interpreter) Set(name string, value interface{}) error {
+	v, err := vm.FromGo(value)
+	if err != nil {
+		return err
+	}
+	in.env.Define(name, v)
+	return nil
+}
+
+//toGoArgs converts arguments for Go function
+func toGoArgs(args []reflect.Value) []interface{} {
+	ret := make([]interface{}, len(args))
+	for i, arg := range args {
+		ret[i] = vm.ToGo(arg)
+	}
+	return ret
+}
+
+//RegisterFunction defines Go function f as name. see vm.NewGoFunction about supported types of f
+func (in *Interpreter) RegisterFunction(name string, f interface{}) error {
+	fun, err := vm.NewGoFunction(f)
+	if err != nil {
+		return err
+	}
+	in.env.DefineBuiltin(name, reflect.ValueOf(fun))
+	return nil
+}
+
+//RegisterProducer defines function which returns Producer. f runs in its own goroutine when called.
+//nil sent by f is skipped because nil can't flow through stream.
+func (in *Interpreter) RegisterProducer(name string, f ProducerFunc) {
+	env := in.env
+	env.DefineBuiltin(name, reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		goargs := toGoArgs(args)
+		valve := pipe.NewContextValve(env.Context())
+		go func() {
+			defer valve.Close()
+			var converr error
+			err := f(goargs, func(x interface{}) bool {
+				v, err := vm.FromGo(x)
+				if err != nil {
+					converr = err
+					return false
+				}
+				if !v.IsValid() {
+					return true
+				}
+				return valve.Send(v)
+			})
+			if err == nil {
+				err = converr
+			}
+			if err != nil {
+				env.ReportError(vm.Errorf(pos, "%s", err.Error()))
+			}
+		}()
+		return reflect.ValueOf(pipe.NewProducer(valve)), nil
+	})))
+}
+
+//RegisterConsumer defines function which returns Consumer
+func (in *Interpreter) RegisterConsumer(name string, f ConsumerFunc) {
+	env := in.env
+	env.DefineBuiltin(name, reflect.ValueOf(vm.NewBuiltinFunctionAt(func(pos ast.Pos, args ...reflect.Value) (reflect.Value, error) {
+		goargs := toGoArgs(args)
+		return reflect.ValueOf(pipe.NewConsumer(func(r <-chan reflect.Value) reflect.Value {
+			recv := make(chan interface{})
+			stop := make(chan bool)
+			go func() {
+				defer close(recv)
+				for v := range r {
+					select {
+					case recv <- vm.ToGo(v):
+					case <-stop:
+						return
+					}
+				}
+			}()
+			ret, err := f(goargs, recv)
+			close(stop)
+			if err == nil {
+				var v reflect.Value
+				if v, err = vm.FromGo(ret); err == nil {
+					return v
+				}
+			}
+			env.ReportError(vm.Errorf(pos, "%s", err.Error()))
+			return vm.NIL
+		})), nil
+	})))
+}
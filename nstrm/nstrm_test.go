@@ -0,0 +1,263 @@
+package nstrm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"../logging"
+)
+
+func eval(in *Interpreter, src string, t *testing.T) interface{} {
+	v, err := in.Eval(context.Background(), src)
+	if err != nil {
+		t.Fatalf("%s: %v", src, err)
+	}
+	return v
+}
+
+func TestEval(t *testing.T) {
+	in := New()
+	defer in.Close()
+	if v := eval(in, `x = 1+2;x*2`, t); v != int64(6) {
+		t.Errorf("got %#v", v)
+	}
+	if v := eval(in, `x/4.0`, t); v != 0.75 {
+		t.Errorf("variable must remain. got %#v", v)
+	}
+	if v := eval(in, `seq(3) | {x -> "${x}"} | collect()`, t); !reflect.DeepEqual(v, []interface{}{"1", "2", "3"}) {
+		t.Errorf("got %#v", v)
+	}
+	if v := eval(in, `g = {-> later * 2}`, t); v == nil {
+		t.Errorf("function must be defined before variable which it refers")
+	}
+	if v := eval(in, `later = 5; g()`, t); v != int64(10) {
+		t.Errorf("got %#v", v)
+	}
+	if _, err := in.Eval(context.Background(), `undefined()`); err == nil || !strings.Contains(err.Error(), "undefined is undefined") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := in.Eval(context.Background(), `[1] | {x -> x + nil} | STDOUT`); err == nil {
+		t.Errorf("error in pipe must be returned")
+	}
+}
+
+func TestGetSet(t *testing.T) {
+	in := New()
+	defer in.Close()
+	if err := in.Set("xs", []int{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	eval(in, `total = xs | sum()`, t)
+	if v, ok := in.Get("total"); !ok || v != int64(6) {
+		t.Errorf("got %#v %v", v, ok)
+	}
+	if _, ok := in.Get("missing"); ok {
+		t.Errorf("missing variable must not be found")
+	}
+	n := 1
+	for _, value := range []interface{}{struct{ X int }{1}, make(chan int), &n, []interface{}{&n}} {
+		if err := in.Set("bad", value); err == nil || !strings.Contains(err.Error(), "unsupported type") {
+			t.Errorf("%T must be unsupported. got %v", value, err)
+		}
+	}
+	var nilptr *int
+	if err := in.Set("nilptr", nilptr); err != nil {
+		t.Errorf("nil pointer must be nil. got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	in := New()
+	defer in.Close()
+	in.RegisterFunction("greet", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("greet needs a name")
+		}
+		return fmt.Sprintf("hello %v", args[0]), nil
+	})
+	in.RegisterProducer("counter", func(args []interface{}, send func(interface{}) bool) error {
+		for i := int64(0); i < args[0].(int64); i++ {
+			if !send(i) {
+				return nil
+			}
+		}
+		return nil
+	})
+	var got []interface{}
+	in.RegisterConsumer("store", func(args []interface{}, recv <-chan interface{}) (interface{}, error) {
+		for v := range recv {
+			got = append(got, v)
+		}
+		return len(got), nil
+	})
+	if v := eval(in, `greet("nstrm")`, t); v != "hello nstrm" {
+		t.Errorf("got %#v", v)
+	}
+	if _, err := in.Eval(context.Background(), `greet()`); err == nil || !strings.Contains(err.Error(), "greet needs a name") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if v := eval(in, `counter(5) | {x -> x * 10} | store()`, t); v != int64(5) {
+		t.Errorf("got %#v", v)
+	}
+	if !reflect.DeepEqual(got, []interface{}{int64(0), int64(10), int64(20), int64(30), int64(40)}) {
+		t.Errorf("got %#v", got)
+	}
+	if v := eval(in, `counter(1000000) | take(2) | sum()`, t); v != int64(1) {
+		t.Errorf("got %#v", v)
+	}
+}
+
+func TestRegisterGoFunction(t *testing.T) {
+	in := New()
+	defer in.Close()
+	err := in.RegisterFunction("repeat", func(s string, n int) (string, error) {
+		if n < 0 {
+			return "", fmt.Errorf("negative count")
+		}
+		return strings.Repeat(s, n), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.RegisterFunction("total", func(xs []float64, scale ...int) float64 {
+		ret := 0.0
+		for _, x := range xs {
+			ret += x
+		}
+		for _, s := range scale {
+			ret *= float64(s)
+		}
+		return ret
+	})
+	in.RegisterFunction("keys", func(m map[string]int) int {
+		return len(m)
+	})
+	in.RegisterFunction("at", func(xs []int, i int) int {
+		return xs[i]
+	})
+	if v := eval(in, `repeat("ab",3)`, t); v != "ababab" {
+		t.Errorf("got %#v", v)
+	}
+	if v := eval(in, `total([1,2.5],2)`, t); v != 7.0 {
+		t.Errorf("got %#v", v)
+	}
+	if v := eval(in, `keys({a: 1, b: 2})`, t); v != int64(2) {
+		t.Errorf("got %#v", v)
+	}
+	errors := map[string]string{
+		`repeat("ab")`:                      "wrong number of argments",
+		`repeat("ab","x")`:                  "argment 2",
+		`repeat("ab",1.5)`:                  "can't be int",
+		`repeat("ab",0-1)`:                  "negative count",
+		`at([1],5)`:                         "index out of range",
+		`[1] | {x -> at([],x)} | collect()`: "Column: 12",
+	}
+	for src, msg := range errors {
+		if _, err := in.Eval(context.Background(), src); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: expected %q got %v", src, msg, err)
+		}
+	}
+	if err := in.RegisterFunction("bad", 1); err == nil {
+		t.Errorf("non function must be error")
+	}
+}
+
+func TestEvalContext(t *testing.T) {
+	in := New()
+	defer in.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := in.Eval(ctx, `1`); err != context.Canceled {
+		t.Errorf("got %v", err)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := in.Eval(ctx, `after(200) | collect()`); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestCancelStopsScript(t *testing.T) {
+	in := New()
+	defer in.Close()
+	//nothing writes to chan. its valves must be closed by cancel
+	for _, src := range []string{`interval(1) | count()`, `i = 0;while true {i = i + 1}`, `c = chan(); c | {x -> x} | count()`} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		if _, err := in.Eval(ctx, src); err != context.DeadlineExceeded {
+			t.Errorf("%s: got %v", src, err)
+		}
+		cancel()
+	}
+	//next Eval waits until the cancelled script ends
+	if v := eval(in, `seq(3) | sum()`, t); v != int64(6) {
+		t.Errorf("interpreter must be usable after cancel. got %#v", v)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	in := New()
+	defer in.Close()
+	var buf bytes.Buffer
+	in.SetLogger(logging.New(&buf, logging.Debug, false))
+	eval(in, `seq(2) | {x -> x} | collect()`, t)
+	for _, expected := range []string{"stage=producer", "stage=consumer", "value=2", "pipe=filter#", "seq close", "ConnectPF", "ConnectPC"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("%q is not in logs\n%s", expected, buf.String())
+		}
+	}
+	//nil discards logs
+	in.SetLogger(nil)
+	eval(in, `seq(2) | last()`, t)
+}
+
+func TestImportLogger(t *testing.T) {
+	dir := module(`f = {-> seq(2) | last()}`, t)
+	defer os.RemoveAll(dir)
+	in := New(dir)
+	defer in.Close()
+	eval(in, `import "lib.nstrm" as l`, t)
+	//module logs with Logger set after import
+	var buf bytes.Buffer
+	in.SetLogger(logging.New(&buf, logging.Debug, false))
+	eval(in, `l.f()`, t)
+	if !strings.Contains(buf.String(), "stage=consumer") {
+		t.Errorf("module must log with current Logger\n%s", buf.String())
+	}
+}
+
+//module writes src as lib.nstrm in new directory and returns the directory
+func module(src string, t *testing.T) string {
+	dir, err := ioutil.TempDir("", "nstrm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "lib.nstrm"), []byte(src), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestImportAfterCancel(t *testing.T) {
+	dir := module(`twice = {x -> seq(x) | {y -> x} | sum()}`, t)
+	defer os.RemoveAll(dir)
+	in := New(dir)
+	defer in.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	if v, err := in.Eval(ctx, `import "lib.nstrm" as l; l.twice(2)`); err != nil || v != int64(4) {
+		t.Fatalf("got %#v, %v", v, err)
+	}
+	cancel()
+	//module uses context of the current Eval, not the one it was imported with
+	if v := eval(in, `l.twice(3)`, t); v != int64(9) {
+		t.Errorf("got %#v", v)
+	}
+}
@@ -1,9 +1,8 @@
 package main
 
 import (
-	"io/ioutil"
-	"log"
 	"reflect"
+	"strings"
 	"sync"
 	"testing"
 
@@ -22,7 +21,6 @@ func assertNum(expr string, expected string, t *testing.T) {
 		return
 	}
 	p.Execute()
-	log.SetOutput(ioutil.Discard)
 	env := vm.NewEnv(&wg)
 	builtins.LoadCore(env)
 	n, _ := vm.SscanNumber(expected)
@@ -35,6 +33,21 @@ func assertNum(expr string, expected string, t *testing.T) {
 	}
 }
 
+//assertError runs expr and checks that it fails with error which contains expected
+func assertError(expr string, expected string, t *testing.T) {
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	defer func() {
+		env.RunWait(vm.NIL)
+		env.Decref()
+		wg.Wait()
+	}()
+	if _, err := eval(expr, env); err == nil || !strings.Contains(err.Error(), expected) {
+		t.Errorf("%s: expected error %q. got %v", expr, expected, err)
+	}
+}
+
 // test add
 
 func TestADDii(t *testing.T) {
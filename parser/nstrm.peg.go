@@ -23,6 +23,9 @@ const (
 	rulee2
 	rulee3
 	rulee4
+	rulepostfix
+	ruleindex
+	ruleslice
 	rulevalue
 	ruleidentifer
 	ruleidentifer_prepare
@@ -31,6 +34,9 @@ const (
 	rulerefvariable
 	rulefuncall
 	rulearray
+	rulemap
+	rulemapentry
+	rulemapkey
 	ruleblock
 	ruleifexpr
 	rulewhileexpr
@@ -39,6 +45,17 @@ const (
 	rulefloating
 	ruleinteger
 	rulestringliteral
+	rulestrpart
+	rulestringchars
+	rulestrchar
+	ruleescape
+	rulehexdigit
+	ruleinterp
+	rulequalified
+	rulequalified_prepare
+	ruleimportexpr
+	ruleimportpath
+	ruleimportalias
 	rulesp
 	rulews
 	rulecomment
@@ -93,6 +110,21 @@ const (
 	ruleAction45
 	ruleAction46
 	ruleAction47
+	ruleAction48
+	ruleAction49
+	ruleAction50
+	ruleAction51
+	ruleAction52
+	ruleAction53
+	ruleAction54
+	ruleAction55
+	ruleAction56
+	ruleAction57
+	ruleAction58
+	ruleAction59
+	ruleAction60
+	ruleAction61
+	ruleAction62
 
 	rulePre_
 	rule_In_
@@ -110,6 +142,9 @@ var rul3s = [...]string{
 	"e2",
 	"e3",
 	"e4",
+	"postfix",
+	"index",
+	"slice",
 	"value",
 	"identifer",
 	"identifer_prepare",
@@ -118,6 +153,9 @@ var rul3s = [...]string{
 	"refvariable",
 	"funcall",
 	"array",
+	"map",
+	"mapentry",
+	"mapkey",
 	"block",
 	"ifexpr",
 	"whileexpr",
@@ -126,6 +164,17 @@ var rul3s = [...]string{
 	"floating",
 	"integer",
 	"stringliteral",
+	"strpart",
+	"stringchars",
+	"strchar",
+	"escape",
+	"hexdigit",
+	"interp",
+	"qualified",
+	"qualified_prepare",
+	"importexpr",
+	"importpath",
+	"importalias",
 	"sp",
 	"ws",
 	"comment",
@@ -180,6 +229,21 @@ var rul3s = [...]string{
 	"Action45",
 	"Action46",
 	"Action47",
+	"Action48",
+	"Action49",
+	"Action50",
+	"Action51",
+	"Action52",
+	"Action53",
+	"Action54",
+	"Action55",
+	"Action56",
+	"Action57",
+	"Action58",
+	"Action59",
+	"Action60",
+	"Action61",
+	"Action62",
 
 	"Pre_",
 	"_In_",
@@ -748,7 +812,7 @@ type Nstrm struct {
 
 	Buffer string
 	buffer []rune
-	rules  [80]func() bool
+	rules  [112]func() bool
 	Parse  func(rule ...int) error
 	Reset  func()
 	tokenTree
@@ -920,8 +984,37 @@ func (p *Nstrm) Execute() {
 		case ruleAction46:
 			p.addNumber(buffer[begin:end], begin, end)
 		case ruleAction47:
-			s, _ := strconv.Unquote(buffer[begin:end])
-			p.literal(s, begin, end)
+			p.stringliteral(begin, end)
+		case ruleAction48:
+			p.pushScope()
+		case ruleAction49:
+			p.mapexpr()
+		case ruleAction50:
+			p.literal(buffer[begin:end], begin, end)
+		case ruleAction51:
+			p.pushScope()
+		case ruleAction52:
+			p.index(begin, end)
+		case ruleAction53:
+			p.pushScope()
+		case ruleAction54:
+			p.sliceFrom()
+		case ruleAction55:
+			p.slice(begin, end)
+		case ruleAction56:
+			p.pushScope()
+		case ruleAction57:
+			p.stringchars(buffer[begin:end], begin, end)
+		case ruleAction58:
+			p.prepare(buffer[begin:end])
+		case ruleAction59:
+			p.pushScope()
+		case ruleAction60:
+			p.importexpr()
+		case ruleAction61:
+			p.importPath(buffer[begin:end], begin, end)
+		case ruleAction62:
+			p.importAlias(buffer[begin:end])
 
 		}
 	}
@@ -1578,7 +1671,7 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position63, tokenIndex63, depth63
 			return false
 		},
-		/* 8 e4 <- <(value ((&('#') comment) | (&('\t') '\t') | (&(' ') ' '))*)> */
+		/* 8 e4 <- <(value postfix* ((&('#') comment) | (&('\t') '\t') | (&(' ') ' '))*)> */
 		func() bool {
 			position71, tokenIndex71, depth71 := position, tokenIndex, depth
 			{
@@ -1957,6 +2050,12 @@ func (p *Nstrm) Init() {
 						}
 						goto l74
 					l115:
+						position, tokenIndex, depth = position74, tokenIndex74, depth74
+						if !_rules[ruleimportexpr]() {
+							goto l352
+						}
+						goto l74
+					l352:
 						position, tokenIndex, depth = position74, tokenIndex74, depth74
 						{
 							position119 := position
@@ -1964,7 +2063,7 @@ func (p *Nstrm) Init() {
 							{
 								position120 := position
 								depth++
-								if !_rules[ruleidentifer_prepare]() {
+								if !_rules[rulequalified_prepare]() {
 									goto l118
 								}
 								if buffer[position] != rune('(') {
@@ -2062,72 +2161,82 @@ func (p *Nstrm) Init() {
 								break
 							case '{':
 								{
-									position130 := position
-									depth++
-									if buffer[position] != rune('{') {
-										goto l71
-									}
-									position++
-									{
-										add(ruleAction31, position)
-									}
-									if !_rules[rulesp]() {
-										goto l71
+									position282, tokenIndex282, depth282 := position, tokenIndex, depth
+									if !_rules[rulemap]() {
+										goto l283
 									}
-								l132:
+									goto l282
+								l283:
+									position, tokenIndex, depth = position282, tokenIndex282, depth282
 									{
-										position133, tokenIndex133, depth133 := position, tokenIndex, depth
+										position130 := position
+										depth++
+										if buffer[position] != rune('{') {
+											goto l71
+										}
+										position++
+										{
+											add(ruleAction31, position)
+										}
 										if !_rules[rulesp]() {
-											goto l133
+											goto l71
 										}
-										if !_rules[ruleidentifer_argment]() {
-											goto l133
+									l132:
+										{
+											position133, tokenIndex133, depth133 := position, tokenIndex, depth
+											if !_rules[rulesp]() {
+												goto l133
+											}
+											if !_rules[ruleidentifer_argment]() {
+												goto l133
+											}
+											if !_rules[rulesp]() {
+												goto l133
+											}
+											if buffer[position] != rune(',') {
+												goto l133
+											}
+											position++
+											goto l132
+										l133:
+											position, tokenIndex, depth = position133, tokenIndex133, depth133
+										}
+										{
+											position134, tokenIndex134, depth134 := position, tokenIndex, depth
+											if !_rules[ruleidentifer_argment]() {
+												goto l134
+											}
+											goto l135
+										l134:
+											position, tokenIndex, depth = position134, tokenIndex134, depth134
 										}
+									l135:
 										if !_rules[rulesp]() {
-											goto l133
+											goto l71
 										}
-										if buffer[position] != rune(',') {
-											goto l133
+										if buffer[position] != rune('-') {
+											goto l71
 										}
 										position++
-										goto l132
-									l133:
-										position, tokenIndex, depth = position133, tokenIndex133, depth133
-									}
-									{
-										position134, tokenIndex134, depth134 := position, tokenIndex, depth
-										if !_rules[ruleidentifer_argment]() {
-											goto l134
+										if buffer[position] != rune('>') {
+											goto l71
 										}
-										goto l135
-									l134:
-										position, tokenIndex, depth = position134, tokenIndex134, depth134
-									}
-								l135:
-									if !_rules[rulesp]() {
-										goto l71
-									}
-									if buffer[position] != rune('-') {
-										goto l71
-									}
-									position++
-									if buffer[position] != rune('>') {
-										goto l71
-									}
-									position++
-									if !_rules[rulebody]() {
-										goto l71
-									}
-									if buffer[position] != rune('}') {
-										goto l71
-									}
-									position++
-									{
-										add(ruleAction32, position)
+										position++
+										if !_rules[rulebody]() {
+											goto l71
+										}
+										if buffer[position] != rune('}') {
+											goto l71
+										}
+										position++
+										{
+											add(ruleAction32, position)
+										}
+										depth--
+										add(ruleblock, position130)
 									}
-									depth--
-									add(ruleblock, position130)
 								}
+							l282:
 								break
 							case '[':
 								{
@@ -2188,90 +2297,13 @@ func (p *Nstrm) Init() {
 								}
 								break
 							case '"':
-								{
-									position144 := position
-									depth++
-									{
-										position145 := position
-										depth++
-										if buffer[position] != rune('"') {
-											goto l71
-										}
-										position++
-									l146:
-										{
-											position147, tokenIndex147, depth147 := position, tokenIndex, depth
-											{
-												position148, tokenIndex148, depth148 := position, tokenIndex, depth
-												if buffer[position] != rune('"') {
-													goto l148
-												}
-												position++
-												goto l147
-											l148:
-												position, tokenIndex, depth = position148, tokenIndex148, depth148
-											}
-											if !matchDot() {
-												goto l147
-											}
-											goto l146
-										l147:
-											position, tokenIndex, depth = position147, tokenIndex147, depth147
-										}
-										if buffer[position] != rune('"') {
-											goto l71
-										}
-										position++
-										depth--
-										add(rulePegText, position145)
-									}
-									{
-										add(ruleAction47, position)
-									}
-									depth--
-									add(rulestringliteral, position144)
+								if !_rules[rulestringliteral]() {
+									goto l71
 								}
 								break
 							case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-								{
-									position150 := position
-									depth++
-									{
-										position151 := position
-										depth++
-										{
-											position152, tokenIndex152, depth152 := position, tokenIndex, depth
-											if !_rules[ruleminus]() {
-												goto l152
-											}
-											goto l153
-										l152:
-											position, tokenIndex, depth = position152, tokenIndex152, depth152
-										}
-									l153:
-										if c := buffer[position]; c < rune('0') || c > rune('9') {
-											goto l71
-										}
-										position++
-									l154:
-										{
-											position155, tokenIndex155, depth155 := position, tokenIndex, depth
-											if c := buffer[position]; c < rune('0') || c > rune('9') {
-												goto l155
-											}
-											position++
-											goto l154
-										l155:
-											position, tokenIndex, depth = position155, tokenIndex155, depth155
-										}
-										depth--
-										add(rulePegText, position151)
-									}
-									{
-										add(ruleAction46, position)
-									}
-									depth--
-									add(ruleinteger, position150)
+								if !_rules[ruleinteger]() {
+									goto l71
 								}
 								break
 							default:
@@ -2281,7 +2313,7 @@ func (p *Nstrm) Init() {
 									{
 										position158 := position
 										depth++
-										if !_rules[ruleidentifer]() {
+										if !_rules[rulequalified]() {
 											goto l71
 										}
 										depth--
@@ -2302,6 +2334,16 @@ func (p *Nstrm) Init() {
 					depth--
 					add(rulevalue, position73)
 				}
+			l298:
+				{
+					position299, tokenIndex299, depth299 := position, tokenIndex, depth
+					if !_rules[rulepostfix]() {
+						goto l299
+					}
+					goto l298
+				l299:
+					position, tokenIndex, depth = position299, tokenIndex299, depth299
+				}
 			l160:
 				{
 					position161, tokenIndex161, depth161 := position, tokenIndex, depth
@@ -2339,9 +2381,152 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position71, tokenIndex71, depth71
 			return false
 		},
-		/* 9 value <- <(floating / ifexpr / whileexpr / emit / ('s' 'k' 'i' 'p' Action18) / ('c' 'l' 'o' 's' 'e' Action19 ws expr? Action20) / (<('n' 'i' 'l')> Action21) / (<('t' 'r' 'u' 'e')> Action22) / (<('f' 'a' 'l' 's' 'e')> Action23) / wait / funcall / bind / ((&('(') ('(' sp expr sp ')')) | (&('{') block) | (&('[') array) | (&('"') stringliteral) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') integer) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z' | '_' | 'a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') refvariable)))> */
+		/* 9 postfix <- <(index / slice)> */
+		func() bool {
+			position284, tokenIndex284, depth284 := position, tokenIndex, depth
+			{
+				position285 := position
+				depth++
+				{
+					position286, tokenIndex286, depth286 := position, tokenIndex, depth
+					if !_rules[ruleindex]() {
+						goto l287
+					}
+					goto l286
+				l287:
+					position, tokenIndex, depth = position286, tokenIndex286, depth286
+					if !_rules[ruleslice]() {
+						goto l284
+					}
+				}
+			l286:
+				depth--
+				add(rulepostfix, position285)
+			}
+			return true
+		l284:
+			position, tokenIndex, depth = position284, tokenIndex284, depth284
+			return false
+		},
+		/* 10 index <- <(<('[' Action51 sp expr sp ']')> Action52)> */
+		func() bool {
+			position288, tokenIndex288, depth288 := position, tokenIndex, depth
+			{
+				position289 := position
+				depth++
+				{
+					position290 := position
+					depth++
+					if buffer[position] != rune('[') {
+						goto l288
+					}
+					position++
+					{
+						add(ruleAction51, position)
+					}
+					if !_rules[rulesp]() {
+						goto l288
+					}
+					if !_rules[ruleexpr]() {
+						goto l288
+					}
+					if !_rules[rulesp]() {
+						goto l288
+					}
+					if buffer[position] != rune(']') {
+						goto l288
+					}
+					position++
+					depth--
+					add(rulePegText, position290)
+				}
+				{
+					add(ruleAction52, position)
+				}
+				depth--
+				add(ruleindex, position289)
+			}
+			return true
+		l288:
+			position, tokenIndex, depth = position288, tokenIndex288, depth288
+			return false
+		},
+		/* 11 slice <- <(<('[' Action53 sp expr? sp ':' Action54 sp expr? sp ']')> Action55)> */
+		func() bool {
+			position291, tokenIndex291, depth291 := position, tokenIndex, depth
+			{
+				position292 := position
+				depth++
+				{
+					position293 := position
+					depth++
+					if buffer[position] != rune('[') {
+						goto l291
+					}
+					position++
+					{
+						add(ruleAction53, position)
+					}
+					if !_rules[rulesp]() {
+						goto l291
+					}
+					{
+						position294, tokenIndex294, depth294 := position, tokenIndex, depth
+						if !_rules[ruleexpr]() {
+							goto l294
+						}
+						goto l295
+					l294:
+						position, tokenIndex, depth = position294, tokenIndex294, depth294
+					}
+				l295:
+					if !_rules[rulesp]() {
+						goto l291
+					}
+					if buffer[position] != rune(':') {
+						goto l291
+					}
+					position++
+					{
+						add(ruleAction54, position)
+					}
+					if !_rules[rulesp]() {
+						goto l291
+					}
+					{
+						position296, tokenIndex296, depth296 := position, tokenIndex, depth
+						if !_rules[ruleexpr]() {
+							goto l296
+						}
+						goto l297
+					l296:
+						position, tokenIndex, depth = position296, tokenIndex296, depth296
+					}
+				l297:
+					if !_rules[rulesp]() {
+						goto l291
+					}
+					if buffer[position] != rune(']') {
+						goto l291
+					}
+					position++
+					depth--
+					add(rulePegText, position293)
+				}
+				{
+					add(ruleAction55, position)
+				}
+				depth--
+				add(ruleslice, position292)
+			}
+			return true
+		l291:
+			position, tokenIndex, depth = position291, tokenIndex291, depth291
+			return false
+		},
+		/* 12 value <- <(floating / ifexpr / whileexpr / emit / ('s' 'k' 'i' 'p' Action18) / ('c' 'l' 'o' 's' 'e' Action19 ws expr? Action20) / (<('n' 'i' 'l')> Action21) / (<('t' 'r' 'u' 'e')> Action22) / (<('f' 'a' 'l' 's' 'e')> Action23) / wait / importexpr / funcall / bind / ((&('(') ('(' sp expr sp ')')) | (&('{') (map / block)) | (&('[') array) | (&('"') stringliteral) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') integer) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z' | '_' | 'a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') refvariable)))> */
 		nil,
-		/* 10 identifer <- <(((&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('_') '_') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z])) ((&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('_') '_') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))*)> */
+		/* 13 identifer <- <(((&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('_') '_') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z])) ((&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z]) | (&('_') '_') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]))*)> */
 		func() bool {
 			position164, tokenIndex164, depth164 := position, tokenIndex, depth
 			{
@@ -2414,7 +2599,7 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position164, tokenIndex164, depth164
 			return false
 		},
-		/* 11 identifer_prepare <- <(<identifer> sp Action24)> */
+		/* 14 identifer_prepare <- <(<identifer> sp Action24)> */
 		func() bool {
 			position170, tokenIndex170, depth170 := position, tokenIndex, depth
 			{
@@ -2443,7 +2628,7 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position170, tokenIndex170, depth170
 			return false
 		},
-		/* 12 identifer_argment <- <(<identifer> sp Action25)> */
+		/* 15 identifer_argment <- <(<identifer> sp Action25)> */
 		func() bool {
 			position174, tokenIndex174, depth174 := position, tokenIndex, depth
 			{
@@ -2472,17 +2657,153 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position174, tokenIndex174, depth174
 			return false
 		},
-		/* 13 bind <- <(identifer_prepare '=' sp expr Action26)> */
+		/* 16 bind <- <(identifer_prepare '=' sp expr Action26)> */
 		nil,
-		/* 14 refvariable <- <(<identifer> Action27)> */
+		/* 17 refvariable <- <(<qualified> Action27)> */
 		nil,
-		/* 15 funcall <- <(<(identifer_prepare '(' sp (expr ',')* expr? ')')> Action28)> */
+		/* 18 funcall <- <(<(qualified_prepare '(' sp (expr ',')* expr? ')')> Action28)> */
 		nil,
-		/* 16 array <- <('[' Action29 sp (sp expr sp ',')* expr? sp ']' Action30)> */
+		/* 19 array <- <('[' Action29 sp (sp expr sp ',')* expr? sp ']' Action30)> */
 		nil,
-		/* 17 block <- <('{' Action31 sp (sp identifer_argment sp ',')* identifer_argment? sp ('-' '>') body '}' Action32)> */
+		/* 20 map <- <('{' Action48 sp (mapentry sp ',' sp)* mapentry? sp '}' Action49)> */
+		func() bool {
+			position267, tokenIndex267, depth267 := position, tokenIndex, depth
+			{
+				position268 := position
+				depth++
+				if buffer[position] != rune('{') {
+					goto l267
+				}
+				position++
+				{
+					add(ruleAction48, position)
+				}
+				if !_rules[rulesp]() {
+					goto l267
+				}
+			l270:
+				{
+					position271, tokenIndex271, depth271 := position, tokenIndex, depth
+					if !_rules[rulemapentry]() {
+						goto l271
+					}
+					if !_rules[rulesp]() {
+						goto l271
+					}
+					if buffer[position] != rune(',') {
+						goto l271
+					}
+					position++
+					if !_rules[rulesp]() {
+						goto l271
+					}
+					goto l270
+				l271:
+					position, tokenIndex, depth = position271, tokenIndex271, depth271
+				}
+				{
+					position272, tokenIndex272, depth272 := position, tokenIndex, depth
+					if !_rules[rulemapentry]() {
+						goto l272
+					}
+					goto l273
+				l272:
+					position, tokenIndex, depth = position272, tokenIndex272, depth272
+				}
+			l273:
+				if !_rules[rulesp]() {
+					goto l267
+				}
+				if buffer[position] != rune('}') {
+					goto l267
+				}
+				position++
+				{
+					add(ruleAction49, position)
+				}
+				depth--
+				add(rulemap, position268)
+			}
+			return true
+		l267:
+			position, tokenIndex, depth = position267, tokenIndex267, depth267
+			return false
+		},
+		/* 21 mapentry <- <(mapkey sp ':' sp expr)> */
+		func() bool {
+			position274, tokenIndex274, depth274 := position, tokenIndex, depth
+			{
+				position275 := position
+				depth++
+				if !_rules[rulemapkey]() {
+					goto l274
+				}
+				if !_rules[rulesp]() {
+					goto l274
+				}
+				if buffer[position] != rune(':') {
+					goto l274
+				}
+				position++
+				if !_rules[rulesp]() {
+					goto l274
+				}
+				if !_rules[ruleexpr]() {
+					goto l274
+				}
+				depth--
+				add(rulemapentry, position275)
+			}
+			return true
+		l274:
+			position, tokenIndex, depth = position274, tokenIndex274, depth274
+			return false
+		},
+		/* 22 mapkey <- <(stringliteral / integer / (<identifer> Action50))> */
+		func() bool {
+			position276, tokenIndex276, depth276 := position, tokenIndex, depth
+			{
+				position277 := position
+				depth++
+				{
+					position278, tokenIndex278, depth278 := position, tokenIndex, depth
+					if !_rules[rulestringliteral]() {
+						goto l279
+					}
+					goto l278
+				l279:
+					position, tokenIndex, depth = position278, tokenIndex278, depth278
+					if !_rules[ruleinteger]() {
+						goto l280
+					}
+					goto l278
+				l280:
+					position, tokenIndex, depth = position278, tokenIndex278, depth278
+					{
+						position281 := position
+						depth++
+						if !_rules[ruleidentifer]() {
+							goto l276
+						}
+						depth--
+						add(rulePegText, position281)
+					}
+					{
+						add(ruleAction50, position)
+					}
+				}
+			l278:
+				depth--
+				add(rulemapkey, position277)
+			}
+			return true
+		l276:
+			position, tokenIndex, depth = position276, tokenIndex276, depth276
+			return false
+		},
+		/* 23 block <- <('{' Action31 sp (sp identifer_argment sp ',')* identifer_argment? sp ('-' '>') body '}' Action32)> */
 		nil,
-		/* 18 ifexpr <- <('i' 'f' Action33 sp expr Action34 '{' body Action35 '}' (sp ('e' 'l' 's' 'e') sp (('{' body Action36 '}') / (sp ifexpr Action37)))? Action38)> */
+		/* 24 ifexpr <- <('i' 'f' Action33 sp expr Action34 '{' body Action35 '}' (sp ('e' 'l' 's' 'e') sp (('{' body Action36 '}') / (sp ifexpr Action37)))? Action38)> */
 		func() bool {
 			position183, tokenIndex183, depth183 := position, tokenIndex, depth
 			{
@@ -2592,19 +2913,554 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position183, tokenIndex183, depth183
 			return false
 		},
-		/* 19 whileexpr <- <('w' 'h' 'i' 'l' 'e' Action39 sp expr Action40 '{' body Action41 '}')> */
-		nil,
-		/* 20 wait <- <('w' 'a' 'i' 't' Action42)> */
-		nil,
-		/* 21 emit <- <('e' 'm' 'i' 't' Action43 sp (((expr ',' sp)+ expr) / expr) Action44)> */
+		/* 25 whileexpr <- <('w' 'h' 'i' 'l' 'e' Action39 sp expr Action40 '{' body Action41 '}')> */
 		nil,
-		/* 22 floating <- <(<(minus? [0-9]+ '.' [0-9]*)> Action45)> */
+		/* 26 wait <- <('w' 'a' 'i' 't' Action42)> */
 		nil,
-		/* 23 integer <- <(<(minus? [0-9]+)> Action46)> */
+		/* 27 emit <- <('e' 'm' 'i' 't' Action43 sp (((expr ',' sp)+ expr) / expr) Action44)> */
 		nil,
-		/* 24 stringliteral <- <(<('"' (!'"' .)* '"')> Action47)> */
+		/* 28 floating <- <(<(minus? [0-9]+ '.' [0-9]*)> Action45)> */
 		nil,
-		/* 25 sp <- <((&('#') comment) | (&('\r') '\r') | (&('\n') '\n') | (&('\t') '\t') | (&(' ') ' '))*> */
+		/* 29 integer <- <(<(minus? [0-9]+)> Action46)> */
+		func() bool {
+			position150, tokenIndex150, depth150 := position, tokenIndex, depth
+			{
+				position151 := position
+				depth++
+				{
+					position152 := position
+					depth++
+					{
+						position153, tokenIndex153, depth153 := position, tokenIndex, depth
+						if !_rules[ruleminus]() {
+							goto l153
+						}
+						goto l154
+					l153:
+						position, tokenIndex, depth = position153, tokenIndex153, depth153
+					}
+				l154:
+					if c := buffer[position]; c < rune('0') || c > rune('9') {
+						goto l150
+					}
+					position++
+				l155:
+					{
+						position156, tokenIndex156, depth156 := position, tokenIndex, depth
+						if c := buffer[position]; c < rune('0') || c > rune('9') {
+							goto l156
+						}
+						position++
+						goto l155
+					l156:
+						position, tokenIndex, depth = position156, tokenIndex156, depth156
+					}
+					depth--
+					add(rulePegText, position152)
+				}
+				{
+					add(ruleAction46, position)
+				}
+				depth--
+				add(ruleinteger, position151)
+			}
+			return true
+		l150:
+			position, tokenIndex, depth = position150, tokenIndex150, depth150
+			return false
+		},
+		/* 30 stringliteral <- <(<('"' Action56 strpart* '"')> Action47)> */
+		func() bool {
+			position300, tokenIndex300, depth300 := position, tokenIndex, depth
+			{
+				position301 := position
+				depth++
+				{
+					position302 := position
+					depth++
+					if buffer[position] != rune('"') {
+						goto l300
+					}
+					position++
+					{
+						add(ruleAction56, position)
+					}
+				l303:
+					{
+						position304, tokenIndex304, depth304 := position, tokenIndex, depth
+						if !_rules[rulestrpart]() {
+							goto l304
+						}
+						goto l303
+					l304:
+						position, tokenIndex, depth = position304, tokenIndex304, depth304
+					}
+					if buffer[position] != rune('"') {
+						goto l300
+					}
+					position++
+					depth--
+					add(rulePegText, position302)
+				}
+				{
+					add(ruleAction47, position)
+				}
+				depth--
+				add(rulestringliteral, position301)
+			}
+			return true
+		l300:
+			position, tokenIndex, depth = position300, tokenIndex300, depth300
+			return false
+		},
+		/* 31 strpart <- <(stringchars / interp)> */
+		func() bool {
+			position305, tokenIndex305, depth305 := position, tokenIndex, depth
+			{
+				position306 := position
+				depth++
+				{
+					position307, tokenIndex307, depth307 := position, tokenIndex, depth
+					if !_rules[rulestringchars]() {
+						goto l308
+					}
+					goto l307
+				l308:
+					position, tokenIndex, depth = position307, tokenIndex307, depth307
+					if !_rules[ruleinterp]() {
+						goto l305
+					}
+				}
+			l307:
+				depth--
+				add(rulestrpart, position306)
+			}
+			return true
+		l305:
+			position, tokenIndex, depth = position305, tokenIndex305, depth305
+			return false
+		},
+		/* 32 stringchars <- <(<strchar+> Action57)> */
+		func() bool {
+			position322, tokenIndex322, depth322 := position, tokenIndex, depth
+			{
+				position323 := position
+				depth++
+				{
+					position324 := position
+					depth++
+					if !_rules[rulestrchar]() {
+						goto l322
+					}
+				l325:
+					{
+						position326, tokenIndex326, depth326 := position, tokenIndex, depth
+						if !_rules[rulestrchar]() {
+							goto l326
+						}
+						goto l325
+					l326:
+						position, tokenIndex, depth = position326, tokenIndex326, depth326
+					}
+					depth--
+					add(rulePegText, position324)
+				}
+				{
+					add(ruleAction57, position)
+				}
+				depth--
+				add(rulestringchars, position323)
+			}
+			return true
+		l322:
+			position, tokenIndex, depth = position322, tokenIndex322, depth322
+			return false
+		},
+		/* 33 strchar <- <(escape / (!((&('$') '$') | (&('\\') '\\') | (&('"') '"')) .) / ('$' !'{'))> */
+		func() bool {
+			position309, tokenIndex309, depth309 := position, tokenIndex, depth
+			{
+				position310 := position
+				depth++
+				{
+					position311, tokenIndex311, depth311 := position, tokenIndex, depth
+					if !_rules[ruleescape]() {
+						goto l312
+					}
+					goto l311
+				l312:
+					position, tokenIndex, depth = position311, tokenIndex311, depth311
+					switch buffer[position] {
+					case '$', '\\', '"':
+						goto l313
+					}
+					if !matchDot() {
+						goto l313
+					}
+					goto l311
+				l313:
+					position, tokenIndex, depth = position311, tokenIndex311, depth311
+					if buffer[position] != rune('$') {
+						goto l309
+					}
+					position++
+					{
+						position314, tokenIndex314, depth314 := position, tokenIndex, depth
+						if buffer[position] != rune('{') {
+							goto l314
+						}
+						position++
+						goto l309
+					l314:
+						position, tokenIndex, depth = position314, tokenIndex314, depth314
+					}
+				}
+			l311:
+				depth--
+				add(rulestrchar, position310)
+			}
+			return true
+		l309:
+			position, tokenIndex, depth = position309, tokenIndex309, depth309
+			return false
+		},
+		/* 34 escape <- <('\\' ((&('x') ('x' hexdigit hexdigit)) | (&('u') ('u' hexdigit hexdigit hexdigit hexdigit)) | (&('"' | '$' | '\\' | 'a' | 'b' | 'f' | 'n' | 'r' | 't' | 'v') ["$\\abfnrtv])))> */
+		func() bool {
+			position315, tokenIndex315, depth315 := position, tokenIndex, depth
+			{
+				position316 := position
+				depth++
+				if buffer[position] != rune('\\') {
+					goto l315
+				}
+				position++
+				switch buffer[position] {
+				case 'x':
+					position++
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+				case 'u':
+					position++
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+					if !_rules[rulehexdigit]() {
+						goto l315
+					}
+				case '"', '$', '\\', 'a', 'b', 'f', 'n', 'r', 't', 'v':
+					position++
+				default:
+					goto l315
+				}
+				depth--
+				add(ruleescape, position316)
+			}
+			return true
+		l315:
+			position, tokenIndex, depth = position315, tokenIndex315, depth315
+			return false
+		},
+		/* 35 hexdigit <- <((&('A' | 'B' | 'C' | 'D' | 'E' | 'F') [A-F]) | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f') [a-f]) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]))> */
+		func() bool {
+			position317, tokenIndex317, depth317 := position, tokenIndex, depth
+			{
+				position318 := position
+				depth++
+				switch buffer[position] {
+				case 'A', 'B', 'C', 'D', 'E', 'F', 'a', 'b', 'c', 'd', 'e', 'f', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+					position++
+				default:
+					goto l317
+				}
+				depth--
+				add(rulehexdigit, position318)
+			}
+			return true
+		l317:
+			position, tokenIndex, depth = position317, tokenIndex317, depth317
+			return false
+		},
+		/* 36 interp <- <('$' '{' sp expr sp '}')> */
+		func() bool {
+			position327, tokenIndex327, depth327 := position, tokenIndex, depth
+			{
+				position328 := position
+				depth++
+				if buffer[position] != rune('$') {
+					goto l327
+				}
+				position++
+				if buffer[position] != rune('{') {
+					goto l327
+				}
+				position++
+				if !_rules[rulesp]() {
+					goto l327
+				}
+				if !_rules[ruleexpr]() {
+					goto l327
+				}
+				if !_rules[rulesp]() {
+					goto l327
+				}
+				if buffer[position] != rune('}') {
+					goto l327
+				}
+				position++
+				depth--
+				add(ruleinterp, position328)
+			}
+			return true
+		l327:
+			position, tokenIndex, depth = position327, tokenIndex327, depth327
+			return false
+		},
+		/* 37 qualified <- <(identifer ('.' identifer)*)> */
+		func() bool {
+			position329, tokenIndex329, depth329 := position, tokenIndex, depth
+			{
+				position330 := position
+				depth++
+				if !_rules[ruleidentifer]() {
+					goto l329
+				}
+			l331:
+				{
+					position332, tokenIndex332, depth332 := position, tokenIndex, depth
+					if buffer[position] != rune('.') {
+						goto l332
+					}
+					position++
+					if !_rules[ruleidentifer]() {
+						goto l332
+					}
+					goto l331
+				l332:
+					position, tokenIndex, depth = position332, tokenIndex332, depth332
+				}
+				depth--
+				add(rulequalified, position330)
+			}
+			return true
+		l329:
+			position, tokenIndex, depth = position329, tokenIndex329, depth329
+			return false
+		},
+		/* 38 qualified_prepare <- <(<qualified> sp Action58)> */
+		func() bool {
+			position333, tokenIndex333, depth333 := position, tokenIndex, depth
+			{
+				position334 := position
+				depth++
+				{
+					position335 := position
+					depth++
+					if !_rules[rulequalified]() {
+						goto l333
+					}
+					depth--
+					add(rulePegText, position335)
+				}
+				if !_rules[rulesp]() {
+					goto l333
+				}
+				{
+					add(ruleAction58, position)
+				}
+				depth--
+				add(rulequalified_prepare, position334)
+			}
+			return true
+		l333:
+			position, tokenIndex, depth = position333, tokenIndex333, depth333
+			return false
+		},
+		/* 39 importexpr <- <('i' 'm' 'p' 'o' 'r' 't' Action59 ws importpath (ws ('a' 's') ((&('\t') '\t') | (&(' ') ' ')) ws importalias)? Action60)> */
+		func() bool {
+			position336, tokenIndex336, depth336 := position, tokenIndex, depth
+			{
+				position337 := position
+				depth++
+				if buffer[position] != rune('i') {
+					goto l336
+				}
+				position++
+				if buffer[position] != rune('m') {
+					goto l336
+				}
+				position++
+				if buffer[position] != rune('p') {
+					goto l336
+				}
+				position++
+				if buffer[position] != rune('o') {
+					goto l336
+				}
+				position++
+				if buffer[position] != rune('r') {
+					goto l336
+				}
+				position++
+				if buffer[position] != rune('t') {
+					goto l336
+				}
+				position++
+				{
+					add(ruleAction59, position)
+				}
+				if !_rules[rulews]() {
+					goto l336
+				}
+				if !_rules[ruleimportpath]() {
+					goto l336
+				}
+				{
+					position339, tokenIndex339, depth339 := position, tokenIndex, depth
+					if !_rules[rulews]() {
+						goto l339
+					}
+					if buffer[position] != rune('a') {
+						goto l339
+					}
+					position++
+					if buffer[position] != rune('s') {
+						goto l339
+					}
+					position++
+					{
+						switch buffer[position] {
+						case '\t':
+							position++
+							break
+						default:
+							if buffer[position] != rune(' ') {
+								goto l339
+							}
+							position++
+							break
+						}
+					}
+
+					if !_rules[rulews]() {
+						goto l339
+					}
+					if !_rules[ruleimportalias]() {
+						goto l339
+					}
+					goto l340
+				l339:
+					position, tokenIndex, depth = position339, tokenIndex339, depth339
+				}
+			l340:
+				{
+					add(ruleAction60, position)
+				}
+				depth--
+				add(ruleimportexpr, position337)
+			}
+			return true
+		l336:
+			position, tokenIndex, depth = position336, tokenIndex336, depth336
+			return false
+		},
+		/* 40 importpath <- <('"' <(!((&('\n') '\n') | (&('"') '"')) .)*> '"' Action61)> */
+		func() bool {
+			position342, tokenIndex342, depth342 := position, tokenIndex, depth
+			{
+				position343 := position
+				depth++
+				if buffer[position] != rune('"') {
+					goto l342
+				}
+				position++
+				{
+					position344 := position
+					depth++
+				l345:
+					{
+						position346, tokenIndex346, depth346 := position, tokenIndex, depth
+						{
+							position347, tokenIndex347, depth347 := position, tokenIndex, depth
+							{
+								switch buffer[position] {
+								case '\n':
+									position++
+									break
+								default:
+									if buffer[position] != rune('"') {
+										goto l347
+									}
+									position++
+									break
+								}
+							}
+
+							goto l346
+						l347:
+							position, tokenIndex, depth = position347, tokenIndex347, depth347
+						}
+						if !matchDot() {
+							goto l346
+						}
+						goto l345
+					l346:
+						position, tokenIndex, depth = position346, tokenIndex346, depth346
+					}
+					depth--
+					add(rulePegText, position344)
+				}
+				if buffer[position] != rune('"') {
+					goto l342
+				}
+				position++
+				{
+					add(ruleAction61, position)
+				}
+				depth--
+				add(ruleimportpath, position343)
+			}
+			return true
+		l342:
+			position, tokenIndex, depth = position342, tokenIndex342, depth342
+			return false
+		},
+		/* 41 importalias <- <(<identifer> Action62)> */
+		func() bool {
+			position349, tokenIndex349, depth349 := position, tokenIndex, depth
+			{
+				position350 := position
+				depth++
+				{
+					position351 := position
+					depth++
+					if !_rules[ruleidentifer]() {
+						goto l349
+					}
+					depth--
+					add(rulePegText, position351)
+				}
+				{
+					add(ruleAction62, position)
+				}
+				depth--
+				add(ruleimportalias, position350)
+			}
+			return true
+		l349:
+			position, tokenIndex, depth = position349, tokenIndex349, depth349
+			return false
+		},
+		/* 42 sp <- <((&('#') comment) | (&('\r') '\r') | (&('\n') '\n') | (&('\t') '\t') | (&(' ') ' '))*> */
 		func() bool {
 			{
 				position202 := position
@@ -2655,7 +3511,7 @@ func (p *Nstrm) Init() {
 			}
 			return true
 		},
-		/* 26 ws <- <(' ' / '\t')*> */
+		/* 43 ws <- <(' ' / '\t')*> */
 		func() bool {
 			{
 				position207 := position
@@ -2687,7 +3543,7 @@ func (p *Nstrm) Init() {
 			}
 			return true
 		},
-		/* 27 comment <- <('#' (!'\n' .)* '\n'?)> */
+		/* 44 comment <- <('#' (!'\n' .)* '\n'?)> */
 		func() bool {
 			position212, tokenIndex212, depth212 := position, tokenIndex, depth
 			{
@@ -2736,100 +3592,100 @@ func (p *Nstrm) Init() {
 			position, tokenIndex, depth = position212, tokenIndex212, depth212
 			return false
 		},
-		/* 28 period <- <((&('#') comment) | (&('\r') '\r') | (&('\n') '\n') | (&(';') ';'))> */
+		/* 45 period <- <((&('#') comment) | (&('\r') '\r') | (&('\n') '\n') | (&(';') ';'))> */
 		nil,
-		/* 30 Action0 <- <{p.Current.FirstFilter=true}> */
+		/* 47 Action0 <- <{p.Current.FirstFilter=true}> */
 		nil,
-		/* 31 Action1 <- <{ p.pipeStart(begin,end) }> */
+		/* 48 Action1 <- <{ p.pipeStart(begin,end) }> */
 		nil,
-		/* 32 Action2 <- <{ p.pipePush(begin,end) }> */
+		/* 49 Action2 <- <{ p.pipePush(begin,end) }> */
 		nil,
-		/* 33 Action3 <- <{p.Current.LastFilter=true}> */
+		/* 50 Action3 <- <{p.Current.LastFilter=true}> */
 		nil,
-		/* 34 Action4 <- <{ p.pipeEnd() }> */
+		/* 51 Action4 <- <{ p.pipeEnd() }> */
 		nil,
-		/* 35 Action5 <- <{ p.addOp2("or",begin,end)}> */
+		/* 52 Action5 <- <{ p.addOp2("or",begin,end)}> */
 		nil,
-		/* 36 Action6 <- <{ p.addOp2("and",begin,end)}> */
+		/* 53 Action6 <- <{ p.addOp2("and",begin,end)}> */
 		nil,
-		/* 37 Action7 <- <{ p.addOp2("==",begin,end) }> */
+		/* 54 Action7 <- <{ p.addOp2("==",begin,end) }> */
 		nil,
-		/* 38 Action8 <- <{ p.addOp2("!=",begin,end) }> */
+		/* 55 Action8 <- <{ p.addOp2("!=",begin,end) }> */
 		nil,
-		/* 39 Action9 <- <{ p.addOp2("<=",begin,end) }> */
+		/* 56 Action9 <- <{ p.addOp2("<=",begin,end) }> */
 		nil,
-		/* 40 Action10 <- <{ p.addOp2(">=",begin,end) }> */
+		/* 57 Action10 <- <{ p.addOp2(">=",begin,end) }> */
 		nil,
-		/* 41 Action11 <- <{ p.addOp2("<" ,begin,end) }> */
+		/* 58 Action11 <- <{ p.addOp2("<" ,begin,end) }> */
 		nil,
-		/* 42 Action12 <- <{ p.addOp2(">" ,begin,end) }> */
+		/* 59 Action12 <- <{ p.addOp2(">" ,begin,end) }> */
 		nil,
-		/* 43 Action13 <- <{ p.addOp2("ADD",begin,end) }> */
+		/* 60 Action13 <- <{ p.addOp2("ADD",begin,end) }> */
 		nil,
-		/* 44 Action14 <- <{ p.addOp2("SUB",begin,end) }> */
+		/* 61 Action14 <- <{ p.addOp2("SUB",begin,end) }> */
 		nil,
-		/* 45 Action15 <- <{ p.addOp2("MUL",begin,end) }> */
+		/* 62 Action15 <- <{ p.addOp2("MUL",begin,end) }> */
 		nil,
-		/* 46 Action16 <- <{ p.addOp2("DIV",begin,end) }> */
+		/* 63 Action16 <- <{ p.addOp2("DIV",begin,end) }> */
 		nil,
-		/* 47 Action17 <- <{ p.addOp2("MOD",begin,end) }> */
+		/* 64 Action17 <- <{ p.addOp2("MOD",begin,end) }> */
 		nil,
-		/* 48 Action18 <- <{ p.skip()  }> */
+		/* 65 Action18 <- <{ p.skip()  }> */
 		nil,
-		/* 49 Action19 <- <{ p.pushScope() }> */
+		/* 66 Action19 <- <{ p.pushScope() }> */
 		nil,
-		/* 50 Action20 <- <{ p.close() }> */
+		/* 67 Action20 <- <{ p.close() }> */
 		nil,
 		nil,
-		/* 52 Action21 <- <{ p.literal(nil,begin,end) }> */
+		/* 69 Action21 <- <{ p.literal(nil,begin,end) }> */
 		nil,
-		/* 53 Action22 <- <{ p.literal(true,begin,end) }> */
+		/* 70 Action22 <- <{ p.literal(true,begin,end) }> */
 		nil,
-		/* 54 Action23 <- <{ p.literal(false,begin,end) }> */
+		/* 71 Action23 <- <{ p.literal(false,begin,end) }> */
 		nil,
-		/* 55 Action24 <- <{ p.prepare(buffer[begin:end]) }> */
+		/* 72 Action24 <- <{ p.prepare(buffer[begin:end]) }> */
 		nil,
-		/* 56 Action25 <- <{ p.addArgment(buffer[begin:end]) }> */
+		/* 73 Action25 <- <{ p.addArgment(buffer[begin:end]) }> */
 		nil,
-		/* 57 Action26 <- <{ p.bind() }> */
+		/* 74 Action26 <- <{ p.bind() }> */
 		nil,
-		/* 58 Action27 <- <{ p.refVar(buffer[begin:end],begin,end) }> */
+		/* 75 Action27 <- <{ p.refVar(buffer[begin:end],begin,end) }> */
 		nil,
-		/* 59 Action28 <- <{ p.funcall(begin,end) }> */
+		/* 76 Action28 <- <{ p.funcall(begin,end) }> */
 		nil,
-		/* 60 Action29 <- <{ p.pushScope() }> */
+		/* 77 Action29 <- <{ p.pushScope() }> */
 		nil,
-		/* 61 Action30 <- <{ p.array() }> */
+		/* 78 Action30 <- <{ p.array() }> */
 		nil,
-		/* 62 Action31 <- <{ p.pushScope() }> */
+		/* 79 Action31 <- <{ p.pushScope() }> */
 		nil,
-		/* 63 Action32 <- <{ p.block() }> */
+		/* 80 Action32 <- <{ p.block() }> */
 		nil,
-		/* 64 Action33 <- <{ p.pushScope() }> */
+		/* 81 Action33 <- <{ p.pushScope() }> */
 		nil,
-		/* 65 Action34 <- <{ p.ifCond() }> */
+		/* 82 Action34 <- <{ p.ifCond() }> */
 		nil,
-		/* 66 Action35 <- <{ p.ifTrue() }> */
+		/* 83 Action35 <- <{ p.ifTrue() }> */
 		nil,
-		/* 67 Action36 <- <{ p.ifElse() }> */
+		/* 84 Action36 <- <{ p.ifElse() }> */
 		nil,
-		/* 68 Action37 <- <{ p.ifElse() }> */
+		/* 85 Action37 <- <{ p.ifElse() }> */
 		nil,
-		/* 69 Action38 <- <{ p.ifexpr() }> */
+		/* 86 Action38 <- <{ p.ifexpr() }> */
 		nil,
-		/* 70 Action39 <- <{ p.pushScope() }> */
+		/* 87 Action39 <- <{ p.pushScope() }> */
 		nil,
-		/* 71 Action40 <- <{ p.whileCond() }> */
+		/* 88 Action40 <- <{ p.whileCond() }> */
 		nil,
-		/* 72 Action41 <- <{ p.whileexpr() }> */
+		/* 89 Action41 <- <{ p.whileexpr() }> */
 		nil,
-		/* 73 Action42 <- <{ p.wait() }> */
+		/* 90 Action42 <- <{ p.wait() }> */
 		nil,
-		/* 74 Action43 <- <{ p.pushScope() }> */
+		/* 91 Action43 <- <{ p.pushScope() }> */
 		nil,
-		/* 75 Action44 <- <{ p.emit() }> */
+		/* 92 Action44 <- <{ p.emit() }> */
 		nil,
-		/* 76 minus <- <> */
+		/* 93 minus <- <> */
 		func() bool {
 			{
 				position266 := position
@@ -2839,11 +3695,41 @@ func (p *Nstrm) Init() {
 			}
 			return true
 		},
-		/* 77 Action45 <- <{ p.addNumber(buffer[begin:end],begin,end) }> */
+		/* 94 Action45 <- <{ p.addNumber(buffer[begin:end],begin,end) }> */
+		nil,
+		/* 95 Action46 <- <{ p.addNumber(buffer[begin:end],begin,end) }> */
+		nil,
+		/* 96 Action47 <- <{ p.stringliteral(begin,end) }> */
+		nil,
+		/* 97 Action48 <- <{ p.pushScope() }> */
+		nil,
+		/* 98 Action49 <- <{ p.mapexpr() }> */
+		nil,
+		/* 99 Action50 <- <{ p.literal(buffer[begin:end],begin,end) }> */
+		nil,
+		/* 100 Action51 <- <{ p.pushScope() }> */
+		nil,
+		/* 101 Action52 <- <{ p.index(begin,end) }> */
+		nil,
+		/* 102 Action53 <- <{ p.pushScope() }> */
+		nil,
+		/* 103 Action54 <- <{ p.sliceFrom() }> */
+		nil,
+		/* 104 Action55 <- <{ p.slice(begin,end) }> */
+		nil,
+		/* 105 Action56 <- <{ p.pushScope() }> */
+		nil,
+		/* 106 Action57 <- <{ p.stringchars(buffer[begin:end],begin,end) }> */
+		nil,
+		/* 107 Action58 <- <{p.prepare(buffer[begin:end]) }> */
+		nil,
+		/* 108 Action59 <- <{ p.pushScope() }> */
+		nil,
+		/* 109 Action60 <- <{ p.importexpr() }> */
 		nil,
-		/* 78 Action46 <- <{ p.addNumber(buffer[begin:end],begin,end) }> */
+		/* 110 Action61 <- <{ p.importPath(buffer[begin:end],begin,end) }> */
 		nil,
-		/* 79 Action47 <- <{ s,_:=strconv.Unquote(buffer[begin:end]);p.literal(s,begin,end) }> */
+		/* 111 Action62 <- <{ p.importAlias(buffer[begin:end]) }> */
 		nil,
 	}
 	p.rules = _rules
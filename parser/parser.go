@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"bytes"
 	"reflect"
+	"strconv"
+	"unicode/utf8"
 
 	"../ast"
 	"../vm"
@@ -16,6 +19,10 @@ type scope struct {
 	IfTrue         []ast.Expr
 	IfElse         []ast.Expr
 	WhileCond      []ast.Expr
+	SliceFrom      []ast.Expr
+	ImportPath     string
+	ImportAlias    string
+	ImportPos      ast.Position
 	FirstFilter    bool
 	LastFilter     bool
 	Pipe           *ast.Pipe
@@ -24,6 +31,8 @@ type scope struct {
 //MyParser is parser for this language
 type MyParser struct {
 	Current *scope
+	//code is compiled by Check
+	code *vm.Code
 }
 
 //Init initializes parser
@@ -73,6 +82,55 @@ func (p *MyParser) literal(lit interface{}, begin int, end int) {
 	p.Current.Stack = append(p.Current.Stack, &ex)
 }
 
+//unescape replaces escape sequences in s. s is already validated by grammar.
+func unescape(s string) string {
+	var buf bytes.Buffer
+	for len(s) > 0 {
+		if len(s) > 1 && s[0] == '\\' && s[1] == '$' {
+			buf.WriteByte('$')
+			s = s[2:]
+			continue
+		}
+		r, multibyte, tail, err := strconv.UnquoteChar(s, '"')
+		if err != nil {
+			buf.WriteString(s)
+			break
+		}
+		if r < utf8.RuneSelf || !multibyte {
+			buf.WriteByte(byte(r))
+		} else {
+			buf.WriteRune(r)
+		}
+		s = tail
+	}
+	return buf.String()
+}
+
+func (p *MyParser) stringchars(raw string, begin int, end int) {
+	p.literal(unescape(raw), begin, end)
+}
+
+//stringliteral makes Literal if there is no interpolation. otherwise makes StringInterp
+func (p *MyParser) stringliteral(begin int, end int) {
+	var buf bytes.Buffer
+	interp := false
+	for _, part := range p.Current.Stack {
+		if l, ok := part.(*ast.Literal); ok && l.Value.Kind() == reflect.String {
+			buf.WriteString(l.Value.String())
+		} else {
+			interp = true
+		}
+	}
+	var ex ast.Expr
+	if interp {
+		ex = &ast.StringInterp{Parts: p.Current.Stack}
+	} else {
+		ex = &ast.Literal{Value: reflect.ValueOf(buf.String())}
+	}
+	ex.SetPosition(ast.Position{Begin: begin, End: end})
+	p.popScope(ex)
+}
+
 func (p *MyParser) refVar(id string, begin int, end int) {
 	ex := ast.RefVar{Identifer: id}
 	ex.SetPosition(ast.Position{Begin: begin, End: end})
@@ -139,6 +197,78 @@ func (p *MyParser) array() {
 	p.popScope(&ex)
 }
 
+func (p *MyParser) mapexpr() {
+	ex := ast.Map{
+		Keys:   []ast.Expr{},
+		Values: []ast.Expr{},
+	}
+	for i := 0; i+1 < len(p.Current.Stack); i += 2 {
+		ex.Keys = append(ex.Keys, p.Current.Stack[i])
+		ex.Values = append(ex.Values, p.Current.Stack[i+1])
+	}
+	p.popScope(&ex)
+}
+
+//postfixTarget pops the expression which index or slice is applied to
+func (p *MyParser) postfixTarget(begin int, end int) (ast.Expr, ast.Position) {
+	parent := p.Current.Parent
+	target := parent.Stack[len(parent.Stack)-1]
+	parent.Stack = parent.Stack[:len(parent.Stack)-1]
+	pos := ast.Position{Begin: begin, End: end}
+	if tpos := target.GetPosition(); tpos.End != 0 && tpos.Begin < begin {
+		pos.Begin = tpos.Begin
+	}
+	return target, pos
+}
+
+func (p *MyParser) index(begin int, end int) {
+	target, pos := p.postfixTarget(begin, end)
+	ex := ast.Index{
+		Target: target,
+		Index:  p.Current.Stack[0],
+	}
+	ex.SetPosition(pos)
+	p.popScope(&ex)
+}
+
+func (p *MyParser) sliceFrom() {
+	p.Current.SliceFrom = p.Current.Stack
+	p.Current.Stack = []ast.Expr{}
+}
+
+func (p *MyParser) slice(begin int, end int) {
+	target, pos := p.postfixTarget(begin, end)
+	ex := ast.Slice{
+		Target: target,
+	}
+	if len(p.Current.SliceFrom) != 0 {
+		ex.From = p.Current.SliceFrom[0]
+	}
+	if len(p.Current.Stack) != 0 {
+		ex.To = p.Current.Stack[0]
+	}
+	ex.SetPosition(pos)
+	p.popScope(&ex)
+}
+
+func (p *MyParser) importPath(path string, begin int, end int) {
+	p.Current.ImportPath = path
+	p.Current.ImportPos = ast.Position{Begin: begin, End: end}
+}
+
+func (p *MyParser) importAlias(alias string) {
+	p.Current.ImportAlias = alias
+}
+
+func (p *MyParser) importexpr() {
+	ex := ast.Import{
+		Path:  p.Current.ImportPath,
+		Alias: p.Current.ImportAlias,
+	}
+	ex.SetPosition(p.Current.ImportPos)
+	p.popScope(&ex)
+}
+
 func (p *MyParser) emit() {
 	ex := ast.Emit{
 		Elements: p.Current.Stack,
@@ -201,14 +331,42 @@ func (p *MyParser) close() {
 	p.popScope(&ast.Close{Ret: p.Current.Stack})
 }
 
-//Run run parsed ast
+//Run compiles parsed ast and runs it. code compiled by Check is reused
 func (p *MyParser) Run(env *vm.Env) (reflect.Value, vm.SpecialValue) {
-	var ret reflect.Value
-	var err vm.SpecialValue
-	for _, expr := range p.Current.Stack {
-		if ret, err = vm.Run(expr, env); err != nil {
-			return ret, err
+	code := p.code
+	if code == nil {
+		var err *vm.Error
+		if code, err = vm.Compile(p.Current.Stack, env); err != nil {
+			return vm.NIL, err
 		}
 	}
-	return ret, err
+	return vm.Exec(code, env)
+}
+
+//RunPart compiles parsed ast as a part of program run in env, such as a line of REPL, and runs it
+func (p *MyParser) RunPart(env *vm.Env) (reflect.Value, vm.SpecialValue) {
+	code, err := vm.CompilePart(p.Current.Stack, env)
+	if err != nil {
+		return vm.NIL, err
+	}
+	return vm.Exec(code, env)
+}
+
+//Check resolves variables of parsed ast in env and returns errors such as undefined variables.
+//parsed ast is compiled once here if there is no error, and Run uses it
+func (p *MyParser) Check(env *vm.Env) []*vm.Error {
+	if errs := vm.Resolve(p.Current.Stack, env); len(errs) > 0 {
+		return errs
+	}
+	code, err := vm.CompileResolved(p.Current.Stack, env)
+	if err != nil {
+		return []*vm.Error{err}
+	}
+	p.code = code
+	return nil
+}
+
+//Interpret runs parsed ast without compiling. it's kept only to compare with Run in benchmarks
+func (p *MyParser) Interpret(env *vm.Env) (reflect.Value, vm.SpecialValue) {
+	return vm.RunList(p.Current.Stack, env)
 }
@@ -2,6 +2,8 @@ package parser
 
 import (
 	"testing"
+
+	"../ast"
 )
 
 func parse(text string, t *testing.T) *MyParser {
@@ -52,3 +54,92 @@ func Test_Fold(t *testing.T) {
 	`
 	parse(expr, t)
 }
+
+func Test_Map(t *testing.T) {
+	expr := `
+	m = {a: 1, "b": [1,2], 3: {x -> x}}
+	{}
+	{
+		a: {b: 1},
+	}
+	`
+	parse(expr, t)
+}
+
+func Test_IndexSlice(t *testing.T) {
+	expr := `
+	a = [1,2,3]
+	a[0] + a[ 1 ]
+	a[1:2]
+	a[:2][0]
+	"abc"[1:]
+	f(1)[0]
+	`
+	parse(expr, t)
+}
+
+func Test_StringLiteral(t *testing.T) {
+	p := parse(`"a\"b\t\$c \u00e9 $"`, t)
+	if l, ok := p.Current.Stack[0].(*ast.Literal); !ok || l.Value.String() != "a\"b\t$c é $" {
+		t.Errorf("unexpected %#v", p.Current.Stack[0])
+	}
+	p = parse(`"x=${x} y=${ {a: "${b}"} }"`, t)
+	if s, ok := p.Current.Stack[0].(*ast.StringInterp); !ok || len(s.Parts) != 4 {
+		t.Errorf("unexpected %#v", p.Current.Stack[0])
+	}
+
+	bad := &Nstrm{Buffer: `"\q"`}
+	bad.Init()
+	if err := bad.Parse(); err == nil {
+		t.Errorf("invalid escape must be parse error")
+	}
+}
+
+func Test_Import(t *testing.T) {
+	p := parse(`import "lib/stream.nstrm";import "x.nstrm" as x;x.fold(1)`, t)
+	if i, ok := p.Current.Stack[0].(*ast.Import); !ok || i.Path != "lib/stream.nstrm" || i.Alias != "" {
+		t.Errorf("unexpected %#v", p.Current.Stack[0])
+	}
+	if i, ok := p.Current.Stack[1].(*ast.Import); !ok || i.Path != "x.nstrm" || i.Alias != "x" {
+		t.Errorf("unexpected %#v", p.Current.Stack[1])
+	}
+	if f, ok := p.Current.Stack[2].(*ast.Funcall); !ok || f.Identifer != "x.fold" {
+		t.Errorf("unexpected %#v", p.Current.Stack[2])
+	}
+	p = parse(`important = 1;important`, t)
+	if r, ok := p.Current.Stack[1].(*ast.RefVar); !ok || r.Identifer != "important" {
+		t.Errorf("unexpected %#v", p.Current.Stack[1])
+	}
+}
+
+func Test_Resolve(t *testing.T) {
+	p := parse("a = 1;f = {x -> y = x;{-> [y,a]}}", t)
+	if errs := p.Check(nil); len(errs) != 0 {
+		t.Fatal(errs[0].Message)
+	}
+	f := p.Current.Stack[1].(*ast.BindVar)
+	if f.Scope != ast.Global {
+		t.Errorf("f must be global. got %v", f.Resolved)
+	}
+	outer := f.Expr.(*ast.Block)
+	if outer.Slots != 2 {
+		t.Errorf("x and y must be slots. got %d", outer.Slots)
+	}
+	inner := outer.Body[1].(*ast.Block).Body[0].(*ast.Array)
+	y := inner.Elements[0].(*ast.RefVar)
+	if y.Resolved != (ast.Resolved{Scope: ast.Local, Depth: 2, Index: 1}) {
+		t.Errorf("unexpected resolution of y %v", y.Resolved)
+	}
+	if a := inner.Elements[1].(*ast.RefVar); a.Scope != ast.Global || a.Depth != 4 {
+		t.Errorf("unexpected resolution of a %v", a.Resolved)
+	}
+
+	p = parse("g = {-> [z]};[w]", t)
+	errs := p.Check(nil)
+	if len(errs) != 2 || errs[0].Message != "z is undefined" || errs[1].Message != "w is undefined" {
+		t.Fatalf("undefined variables must be reported. got %v", errs)
+	}
+	if errs[0].Pos.Begin != 9 {
+		t.Errorf("unexpected position %v", errs[0].Pos)
+	}
+}
@@ -0,0 +1,80 @@
+package pipe
+
+import (
+	"context"
+)
+
+//ctxholder wraps context.Context so that it can be stored in atomic.Value
+type ctxholder struct {
+	context.Context
+}
+
+//setContext sets context of the pipe. its valves are closed when ctx is done
+func (s *stage) setContext(ctx context.Context) {
+	s.ctx.Store(ctxholder{ctx})
+}
+
+//context returns context of the pipe. it's context.Background() until SetContext is called
+func (s *stage) context() context.Context {
+	if h, ok := s.ctx.Load().(ctxholder); ok {
+		return h.Context
+	}
+	return context.Background()
+}
+
+//watch calls cancel if context of the pipe is done before goroutines of the pipe end
+func (s *stage) watch(cancel func()) {
+	ctx := s.context()
+	if ctx.Done() == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.debug("cancel")
+			cancel()
+		case <-s.ended:
+		}
+	}()
+}
+
+func (p *producerChan) SetContext(ctx context.Context) {
+	p.stage.setContext(ctx)
+}
+
+func (f *filterChan) SetContext(ctx context.Context) {
+	f.stage.setContext(ctx)
+}
+
+func (c *consumerFunction) SetContext(ctx context.Context) {
+	c.stage.setContext(ctx)
+}
+
+func (f *pipechan) SetContext(ctx context.Context) {
+	f.stage.setContext(ctx)
+}
+
+func (this *connectedPC) SetContext(ctx context.Context) {
+	this.P.SetContext(ctx)
+	this.C.SetContext(ctx)
+}
+
+func (this *connectedPF) SetContext(ctx context.Context) {
+	this.P.SetContext(ctx)
+	this.F.SetContext(ctx)
+}
+
+func (this *connectedFC) SetContext(ctx context.Context) {
+	this.F.SetContext(ctx)
+	this.C.SetContext(ctx)
+}
+
+func (this *connectedFF) SetContext(ctx context.Context) {
+	this.F1.SetContext(ctx)
+	this.F2.SetContext(ctx)
+}
+
+func (this *port) SetContext(ctx context.Context) {
+	this.P.SetContext(ctx)
+	this.C.SetContext(ctx)
+}
@@ -3,7 +3,6 @@ package pipe
 import (
 	//"github.com/k0kubun/pp"
 
-	"log"
 	"reflect"
 	"sync"
 
@@ -18,39 +17,65 @@ type pipechan struct {
 	ws         []Valve
 	newW       chan Valve
 	exportnewR chan Valve
+	exitnotify chan bool
 	runonce    sync.Once
+	exitonce   sync.Once
+	stoponce   sync.Once
 	runedmutex sync.RWMutex
+	stage      *stage
 	gc.Ref
 }
 
 //NewChan creates new pipechan
 func NewChan() Handle {
+	return NewBufferedChan(0, Block, nil)
+}
+
+//NewBufferedChan creates new pipechan which buffers up to capacity values.
+//see NewBufferedValve about policy and overflow
+func NewBufferedChan(capacity int, policy Policy, overflow func()) Handle {
 	ret := &pipechan{
 		runed:      false,
-		reader:     NewValve(),
+		reader:     NewBufferedValve(capacity, policy, overflow),
 		numsources: 0,
 		done:       make(chan bool),
 		ws:         []Valve{},
 		newW:       make(chan Valve),
 		exportnewR: make(chan Valve),
+		exitnotify: make(chan bool),
 	}
+	ret.stage = newStage("chan", &ret.Ref)
+	ret.stage.input(ret.reader)
+	ret.OnRelease(ret.stage.release)
+	ret.OnRelease(ret.NotifyExit)
 	ret.Incref()
-	go func() {
-		ret.Wait()
-		log.Println("chan exit notify")
-	}()
 	return ret
 }
 
+//NotifyExit tells that no more source is connected. chan ends after all sources end
 func (f *pipechan) NotifyExit() {
+	f.exitonce.Do(func() {
+		close(f.exitnotify)
+	})
+}
+
+//stop stops goroutines of the chan
+func (f *pipechan) stop() {
+	f.stoponce.Do(func() {
+		close(f.done)
+	})
 }
 
 func (f *pipechan) AddW(v Valve) {
 	f.runedmutex.RLock()
 	defer f.runedmutex.RUnlock()
+	f.stage.output(v)
 
 	if f.runed {
-		f.newW <- v
+		select {
+		case f.newW <- v:
+		case <-f.done:
+		}
 	} else {
 		f.ws = append(f.ws, v)
 	}
@@ -61,7 +86,13 @@ func (f *pipechan) NewR() Valve {
 	defer f.runedmutex.RUnlock()
 
 	if f.runed {
-		return <-f.exportnewR
+		select {
+		case r := <-f.exportnewR:
+			return r
+		case <-f.done:
+			//chan is end. reader is closed
+			return f.reader
+		}
 	}
 	f.numsources++
 	return f.reader
@@ -74,23 +105,47 @@ func (f *pipechan) Result() reflect.Value {
 
 func (f *pipechan) Run(*sync.WaitGroup) {
 	f.runonce.Do(func() {
-		log.Println("pipechan run")
+		f.stage.run()
+		f.stage.debug("run")
 		f.runedmutex.Lock()
 		f.runed = true
 		f.runedmutex.Unlock()
 
 		r := make(chan reflect.Value)
 		w := make(chan reflect.Value)
+		var wg sync.WaitGroup
+		wg.Add(3)
+
+		f.stage.watch(func() {
+			f.reader.Close()
+			f.stop()
+		})
 
 		go func() {
+			defer func() {
+				close(w)
+				wg.Done()
+			}()
 			for v := range r {
-				w <- v
+				select {
+				case w <- v:
+				case <-f.done:
+					return
+				}
 			}
 		}()
 
+		//read part. it ends when all sources end after NotifyExit
 		go func() {
+			defer func() {
+				close(r)
+				f.reader.Close()
+				wg.Done()
+			}()
 			buf := []reflect.Value{}
 			rchan := f.reader.Rchan()
+			exitnotify := f.exitnotify
+			exitable := false
 			for {
 				if len(buf) != 0 {
 					select {
@@ -98,10 +153,19 @@ func (f *pipechan) Run(*sync.WaitGroup) {
 						buf = buf[1:]
 					case f.exportnewR <- f.reader:
 						f.numsources++
+					case <-f.done:
+						return
 					}
 				} else {
+					if exitable && f.numsources <= 0 {
+						f.stage.debug("sources end")
+						return
+					}
 					select {
-					case v := <-rchan:
+					case v, ok := <-rchan:
+						if !ok {
+							return
+						}
 						if IsEOF(v) {
 							f.numsources--
 						} else {
@@ -109,19 +173,38 @@ func (f *pipechan) Run(*sync.WaitGroup) {
 						}
 					case f.exportnewR <- f.reader:
 						f.numsources++
+					case <-exitnotify:
+						exitable = true
+						exitnotify = nil
+					case <-f.done:
+						return
 					}
 				}
 			}
 		}()
 
+		//write part. EOF is sent to readers when read part ends
 		go func() {
+			defer func() {
+				for _, valve := range f.ws {
+					valve.Send(EOF)
+				}
+				f.ws = nil
+				f.stop()
+				wg.Done()
+			}()
 			buf := []reflect.Value{}
+			//values are dropped after NotifyExit if there is no reader, because no reader will be connected
+			var drain chan reflect.Value
+			exitnotify := f.exitnotify
 			for {
 				if len(f.ws) != 0 {
 					if len(buf) != 0 {
 						select {
 						case valve := <-f.newW:
 							f.ws = append(f.ws, valve)
+						case <-f.done:
+							return
 						default:
 							valids := []Valve{}
 							for _, valve := range f.ws {
@@ -136,22 +219,41 @@ func (f *pipechan) Run(*sync.WaitGroup) {
 						}
 					} else {
 						select {
-						case value := <-w:
+						case value, ok := <-w:
+							if !ok {
+								return
+							}
 							if IsEOF(value) {
 								panic("should not send EOF")
 							}
 							buf = append(buf, value)
 						case valve := <-f.newW:
 							f.ws = append(f.ws, valve)
+						case <-f.done:
+							return
 						}
 					}
 				} else {
 					select {
 					case valve := <-f.newW:
 						f.ws = append(f.ws, valve)
+					case <-exitnotify:
+						drain = w
+						exitnotify = nil
+					case _, ok := <-drain:
+						if !ok {
+							return
+						}
+					case <-f.done:
+						return
 					}
 				}
 			}
 		}()
+
+		go func() {
+			wg.Wait()
+			f.stage.end()
+		}()
 	})
 }
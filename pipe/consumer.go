@@ -1,11 +1,11 @@
 package pipe
 
 import (
-	"log"
 	"reflect"
 	"sync"
 
 	"../gc"
+	"../logging"
 )
 
 type consumerFunction struct {
@@ -21,6 +21,7 @@ type consumerFunction struct {
 	exitmutex  sync.RWMutex
 	runedmutex sync.Mutex
 	wg         sync.WaitGroup
+	stage      *stage
 	gc.Ref
 }
 
@@ -35,12 +36,11 @@ func NewConsumer(f func(<-chan reflect.Value) reflect.Value) Consumer {
 		exitnotify: make(chan bool, 1),
 	}
 	c.exitmutex.Lock()
+	c.stage = newStage("consumer", &c.Ref)
+	c.stage.input(c.reader)
+	c.OnRelease(c.stage.release)
+	c.OnRelease(c.NotifyExit)
 	c.Incref()
-	go func() {
-		c.Wait()
-		log.Println("consumer end")
-		c.NotifyExit()
-	}()
 	return c
 }
 
@@ -74,55 +74,68 @@ func (c *consumerFunction) Run(wg *sync.WaitGroup) {
 		c.runedmutex.Lock()
 		c.runed = true
 		c.runedmutex.Unlock()
-		log.Println("consumer run", c.wg)
+		c.stage.run()
+		c.stage.debug("run")
 		r := make(chan reflect.Value)
 		w := make(chan reflect.Value)
 		go func() {
 			w <- c.consumer(r)
 		}()
 
+		c.stage.watch(func() {
+			c.reader.Close()
+			c.NotifyExit()
+		})
+
 		go func() {
 			defer func() {
-				log.Println("consumer end", c.wg)
+				c.stage.debug("end", logging.F("result", c.result))
 				c.reader.Close()
 				c.exitmutex.Unlock()
+				c.stage.end()
 				c.wg.Done()
-				log.Println("consumer return", c.result, c.wg)
 			}()
 			exitable := false
+			closed := false
+			closeR := func() {
+				if !closed {
+					closed = true
+					close(r)
+				}
+			}
 			rchan := c.reader.Rchan()
 			for {
 				select {
-				case v := <-rchan:
-					if IsEOF(v) {
+				case v, ok := <-rchan:
+					if !ok {
+						//cancelled. wait for result of the function
+						rchan = nil
+						closeR()
+					} else if IsEOF(v) {
 						c.numsources--
-						log.Println("consumer got eof", c.wg)
+						c.stage.debug("eof", logging.F("sources", c.numsources))
 						if exitable && c.numsources == 0 {
-							log.Println("close r")
-							close(r)
+							closeR()
 						}
 					} else {
-						log.Println("consumer got", v, c)
+						c.stage.debug("received", logging.F("value", v))
 						select {
 						case r <- v:
 						case res := <-w:
-							log.Println("consumer got w", c.wg)
 							c.result = res
 							return
 						}
 					}
 				case res := <-w:
-					log.Println("consumer got w", c.wg)
 					c.result = res
 					return
 				case c.exportnewR <- c.reader:
 					c.numsources++
 				case <-c.exitnotify:
-					log.Println("consumer exit notify", c.numsources, c.wg)
+					c.stage.debug("exit notify", logging.F("sources", c.numsources))
 					exitable = true
 					if c.numsources == 0 {
-						log.Println("close r")
-						close(r)
+						closeR()
 					}
 				}
 			}
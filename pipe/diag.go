@@ -0,0 +1,256 @@
+package pipe
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"../gc"
+)
+
+var diagflag int32
+
+//SetDiagnostics enables diagnostics mode. pipes created after it are recorded so that Dump can show them.
+func SetDiagnostics(on bool) {
+	if on {
+		atomic.StoreInt32(&diagflag, 1)
+	} else {
+		atomic.StoreInt32(&diagflag, 0)
+	}
+}
+
+func diagnostics() bool {
+	return atomic.LoadInt32(&diagflag) == 1
+}
+
+var stageid int64
+var valveid int64
+
+func nextValveID() int64 {
+	return atomic.AddInt64(&valveid, 1)
+}
+
+//stage is identity of producer, filter, consumer or chan. it's used for diagnostics and logs
+type stage struct {
+	id       int64
+	kind     string
+	ref      *gc.Ref
+	tracked  bool
+	state    string
+	released bool
+	in       []Valve
+	out      []Valve
+	log      atomic.Value
+	ctx      atomic.Value
+	ended    chan bool
+	endonce  sync.Once
+}
+
+//stages has pipes which are alive in diagnostics mode
+var stages = struct {
+	sync.Mutex
+	live map[*stage]bool
+}{
+	live: make(map[*stage]bool),
+}
+
+//newStage creates record of pipe. it's recorded only in diagnostics mode
+func newStage(kind string, ref *gc.Ref) *stage {
+	s := &stage{
+		id:      atomic.AddInt64(&stageid, 1),
+		kind:    kind,
+		ref:     ref,
+		tracked: diagnostics(),
+		state:   "created",
+		ended:   make(chan bool),
+	}
+	if s.tracked {
+		stages.Lock()
+		stages.live[s] = true
+		stages.Unlock()
+	}
+	return s
+}
+
+func (s *stage) update(f func()) {
+	if !s.tracked {
+		return
+	}
+	stages.Lock()
+	defer stages.Unlock()
+	f()
+}
+
+//input records valve which the pipe reads
+func (s *stage) input(v Valve) {
+	s.update(func() {
+		s.in = append(s.in, v)
+	})
+}
+
+//output records valve which the pipe writes
+func (s *stage) output(v Valve) {
+	s.update(func() {
+		s.out = append(s.out, v)
+	})
+}
+
+func (s *stage) run() {
+	s.update(func() {
+		if s.state == "created" {
+			s.state = "running"
+			stages.live[s] = true
+		}
+	})
+}
+
+//end is called when goroutines of the pipe end
+func (s *stage) end() {
+	s.endonce.Do(func() {
+		close(s.ended)
+	})
+	s.update(func() {
+		s.state = "ended"
+		delete(stages.live, s)
+	})
+}
+
+func (s *stage) release() {
+	s.update(func() {
+		s.released = true
+		if s.state == "created" {
+			delete(stages.live, s)
+		}
+	})
+}
+
+func (s *stage) String() string {
+	return fmt.Sprintf("%s#%d", s.kind, s.id)
+}
+
+//Dump writes live pipes with their valves and refcounts, and where goroutines are blocked.
+//pipes are recorded only in diagnostics mode. it returns the number of live pipes.
+func Dump(w io.Writer) int {
+	stages.Lock()
+	live := []*stage{}
+	for s := range stages.live {
+		live = append(live, s)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		return live[i].id < live[j].id
+	})
+	fmt.Fprintf(w, "live pipes: %d\n", len(live))
+	for _, s := range live {
+		released := ""
+		if s.released {
+			released = ", released"
+		}
+		fmt.Fprintf(w, "  %s %s, refcount %d%s\n", s, s.state, s.ref.Refcount(), released)
+		for _, v := range s.in {
+			fmt.Fprintf(w, "    in  %s\n", describe(v))
+		}
+		for _, v := range s.out {
+			fmt.Fprintf(w, "    out %s\n", describe(v))
+		}
+	}
+	stages.Unlock()
+
+	total, blocked := goroutines()
+	fmt.Fprintf(w, "goroutines: %d\n", total)
+	for _, line := range blocked {
+		fmt.Fprintf(w, "  %s\n", line)
+	}
+	return len(live)
+}
+
+func describe(v Valve) string {
+	if s, ok := v.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+//goroutines returns the number of goroutines except the caller, and their states and places grouped by them.
+//eg "3 chan receive: pipe.(*filterChan).Run.func2 (pipe/filter.go:98)"
+func goroutines() (int, []string) {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+	counts := map[string]int{}
+	total := 0
+	//the first one is the caller
+	for _, g := range strings.Split(string(buf), "\n\n")[1:] {
+		lines := strings.Split(strings.TrimSpace(g), "\n")
+		//goroutine 5 [chan receive, 2 minutes]:
+		begin := strings.Index(lines[0], "[")
+		end := strings.Index(lines[0], "]")
+		if begin < 0 || end < begin {
+			continue
+		}
+		state := lines[0][begin+1 : end]
+		if i := strings.Index(state, ","); i >= 0 {
+			state = state[:i]
+		}
+		total++
+		counts[state+": "+place(lines[1:])]++
+	}
+	ret := []string{}
+	for key := range counts {
+		ret = append(ret, key)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if counts[ret[i]] != counts[ret[j]] {
+			return counts[ret[i]] > counts[ret[j]]
+		}
+		return ret[i] < ret[j]
+	})
+	for i, key := range ret {
+		ret[i] = fmt.Sprintf("%d %s", counts[key], key)
+	}
+	return total, ret
+}
+
+//place returns the first frame outside of runtime and sync in stack trace of goroutine
+func place(frames []string) string {
+	first := ""
+	for i := 0; i+1 < len(frames); i += 2 {
+		function := frames[i]
+		if strings.HasPrefix(function, "created by ") {
+			break
+		}
+		if j := strings.LastIndex(function, "("); j > 0 && strings.HasSuffix(function, ")") {
+			function = function[:j]
+		}
+		internal := strings.HasPrefix(function, "runtime.") || strings.HasPrefix(function, "sync.") || strings.HasPrefix(function, "internal/")
+		if j := strings.LastIndex(function, "/"); j >= 0 {
+			function = function[j+1:]
+		}
+		fields := strings.Fields(frames[i+1])
+		if len(fields) == 0 {
+			continue
+		}
+		file := filepath.Join(filepath.Base(filepath.Dir(fields[0])), filepath.Base(fields[0]))
+		frame := fmt.Sprintf("%s (%s)", function, file)
+		if !internal {
+			return frame
+		}
+		if first == "" {
+			first = frame
+		}
+	}
+	if first == "" {
+		return "unknown"
+	}
+	return first
+}
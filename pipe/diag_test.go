@@ -0,0 +1,87 @@
+package pipe
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	SetDiagnostics(true)
+	defer SetDiagnostics(false)
+	out := NewValve()
+	p := NewProducer(out)
+	block := make(chan bool)
+	f := NewFilter(func(r <-chan reflect.Value, w Valve) {
+		<-block
+		for v := range r {
+			w.Send(v)
+		}
+	})
+	pf := ConnectPF(p, f)
+	var wg sync.WaitGroup
+	pf.Run(&wg)
+
+	var buf bytes.Buffer
+	//wait until filter function is blocked
+	for i := 0; i < 100; i++ {
+		buf.Reset()
+		if n := Dump(&buf); n != 2 {
+			t.Fatalf("expected 2 live pipes. got %d\n%s", n, buf.String())
+		}
+		if strings.Contains(buf.String(), "diag_test.go") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	for _, expected := range []string{"producer#", "filter#", "running, refcount 1", "out valve#", "open", "(pipe/diag_test.go:"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("%q is not in dump\n%s", expected, buf.String())
+		}
+	}
+
+	close(block)
+	out.Close()
+	p.Decref()
+	f.Decref()
+	pf.Decref()
+	wg.Wait()
+	buf.Reset()
+	if n := Dump(&buf); n != 0 {
+		t.Errorf("expected no live pipes. got %d\n%s", n, buf.String())
+	}
+}
+
+func TestDumpChan(t *testing.T) {
+	SetDiagnostics(true)
+	defer SetDiagnostics(false)
+	out := NewValve()
+	p := NewProducer(out)
+	c := NewChan()
+	pc := ConnectPF(p, c)
+	var wg sync.WaitGroup
+	pc.Run(&wg)
+	c.Run(&wg)
+	go func() {
+		out.Send(reflect.ValueOf(1))
+		out.Close()
+	}()
+	p.Decref()
+	pc.Decref()
+	c.Decref()
+	wg.Wait()
+
+	//chan ends after it's released and its sources end
+	var buf bytes.Buffer
+	for i := 0; i < 100; i++ {
+		buf.Reset()
+		if Dump(&buf) == 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expected no live pipes\n%s", buf.String())
+}
@@ -3,7 +3,6 @@ package pipe
 import (
 	//"github.com/k0kubun/pp"
 
-	"log"
 	"reflect"
 	"sync"
 
@@ -23,6 +22,7 @@ type filterChan struct {
 	runonce    sync.Once
 	runedmutex sync.Mutex
 	wg         sync.WaitGroup
+	stage      *stage
 	gc.Ref
 }
 
@@ -38,11 +38,11 @@ func NewFilter(f func(<-chan reflect.Value, Valve)) Filter {
 		exportnewR: make(chan Valve),
 		exitnotify: make(chan bool, 1),
 	}
+	ret.stage = newStage("filter", &ret.Ref)
+	ret.stage.input(ret.reader)
+	ret.OnRelease(ret.stage.release)
+	ret.OnRelease(ret.NotifyExit)
 	ret.Incref()
-	go func() {
-		ret.Wait()
-		ret.NotifyExit()
-	}()
 	return ret
 }
 
@@ -55,6 +55,7 @@ func (f *filterChan) NotifyExit() {
 func (f *filterChan) AddW(v Valve) {
 	f.runedmutex.Lock()
 	defer f.runedmutex.Unlock()
+	f.stage.output(v)
 	if f.runed {
 		f.newW <- v
 	} else {
@@ -79,7 +80,8 @@ func (f *filterChan) Run(wg *sync.WaitGroup) {
 		f.runed = true
 		f.runedmutex.Unlock()
 		f.wg.Add(3)
-		log.Println("filter run", wg)
+		f.stage.run()
+		f.stage.debug("run")
 		r := make(chan reflect.Value)
 		w := NewValve()
 
@@ -88,6 +90,12 @@ func (f *filterChan) Run(wg *sync.WaitGroup) {
 
 		funend := make(chan bool, 2)
 
+		f.stage.watch(func() {
+			f.reader.Close()
+			w.Close()
+			f.NotifyExit()
+		})
+
 		go func() {
 			<-f.exitnotify
 			go func() { enR <- true }()
@@ -96,10 +104,10 @@ func (f *filterChan) Run(wg *sync.WaitGroup) {
 
 		go func() {
 			f.filter(r, w)
+			f.stage.debug("function end")
 			w.Close()
 			f.wg.Done()
 			funend <- true
-			log.Println("filter func end", wg)
 		}()
 
 		//read part
@@ -113,7 +121,10 @@ func (f *filterChan) Run(wg *sync.WaitGroup) {
 			exitable := false
 			for {
 				select {
-				case v := <-rchan:
+				case v, ok := <-rchan:
+					if !ok {
+						return
+					}
 					if IsEOF(v) {
 						f.numsources--
 						if f.numsources == 0 && exitable {
@@ -200,6 +211,7 @@ func (f *filterChan) Run(wg *sync.WaitGroup) {
 
 	go func() {
 		f.wg.Wait()
+		f.stage.end()
 		wg.Done()
 	}()
 }
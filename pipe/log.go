@@ -0,0 +1,106 @@
+package pipe
+
+import (
+	"fmt"
+
+	"../logging"
+)
+
+//logholder wraps Logger so that it can be stored in atomic.Value
+type logholder struct {
+	logging.Logger
+}
+
+//setLogger sets Logger of the pipe. logs have id and kind of the pipe
+func (s *stage) setLogger(l logging.Logger) {
+	s.log.Store(logholder{l.With(logging.F("pipe", s.String()), logging.F("stage", s.kind))})
+}
+
+//logger returns Logger of the pipe. logs are discarded until SetLogger is called
+func (s *stage) logger() logging.Logger {
+	if h, ok := s.log.Load().(logholder); ok {
+		return h.Logger
+	}
+	return logging.Discard
+}
+
+func (s *stage) debug(msg string, fields ...logging.Field) {
+	s.logger().Log(logging.Debug, msg, fields...)
+}
+
+func (p *producerChan) SetLogger(l logging.Logger) {
+	p.stage.setLogger(l)
+}
+
+func (f *filterChan) SetLogger(l logging.Logger) {
+	f.stage.setLogger(l)
+}
+
+func (c *consumerFunction) SetLogger(l logging.Logger) {
+	c.stage.setLogger(l)
+}
+
+func (f *pipechan) SetLogger(l logging.Logger) {
+	f.stage.setLogger(l)
+}
+
+func (this *connectedPC) SetLogger(l logging.Logger) {
+	this.P.SetLogger(l)
+	this.C.SetLogger(l)
+}
+
+func (this *connectedPF) SetLogger(l logging.Logger) {
+	this.P.SetLogger(l)
+	this.F.SetLogger(l)
+}
+
+func (this *connectedFC) SetLogger(l logging.Logger) {
+	this.F.SetLogger(l)
+	this.C.SetLogger(l)
+}
+
+func (this *connectedFF) SetLogger(l logging.Logger) {
+	this.F1.SetLogger(l)
+	this.F2.SetLogger(l)
+}
+
+func (this *port) SetLogger(l logging.Logger) {
+	this.P.SetLogger(l)
+	this.C.SetLogger(l)
+}
+
+func (p *producerChan) String() string {
+	return p.stage.String()
+}
+
+func (f *filterChan) String() string {
+	return f.stage.String()
+}
+
+func (c *consumerFunction) String() string {
+	return c.stage.String()
+}
+
+func (f *pipechan) String() string {
+	return f.stage.String()
+}
+
+func (this *connectedPC) String() string {
+	return fmt.Sprintf("%s | %s", this.P, this.C)
+}
+
+func (this *connectedPF) String() string {
+	return fmt.Sprintf("%s | %s", this.P, this.F)
+}
+
+func (this *connectedFC) String() string {
+	return fmt.Sprintf("%s | %s", this.F, this.C)
+}
+
+func (this *connectedFF) String() string {
+	return fmt.Sprintf("%s | %s", this.F1, this.F2)
+}
+
+func (this *port) String() string {
+	return fmt.Sprintf("inout(%s, %s)", this.C, this.P)
+}
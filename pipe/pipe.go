@@ -1,11 +1,13 @@
 package pipe
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"reflect"
 	"sync"
 
 	"../gc"
+	"../logging"
 )
 
 //EOF. send EOF instead of close channel
@@ -25,6 +27,7 @@ type Valve interface {
 }
 
 type valveimpl struct {
+	id        int64
 	ch        chan reflect.Value
 	closeonce sync.Once
 	done      chan bool
@@ -35,12 +38,33 @@ type nilvalve struct{}
 //NewValve creates new Valve
 func NewValve() Valve {
 	ret := &valveimpl{
+		id:   nextValveID(),
 		ch:   make(chan reflect.Value),
 		done: make(chan bool),
 	}
 	return ret
 }
 
+//NewContextValve creates new Valve which is closed when ctx is done.
+//producers use it to stop when the program is cancelled
+func NewContextValve(ctx context.Context) Valve {
+	ret := &valveimpl{
+		id:   nextValveID(),
+		ch:   make(chan reflect.Value),
+		done: make(chan bool),
+	}
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				ret.Close()
+			case <-ret.done:
+			}
+		}()
+	}
+	return ret
+}
+
 func NilValve() Valve {
 	return &nilvalve{}
 }
@@ -53,6 +77,19 @@ func (v *valveimpl) Close() {
 
 func (v *nilvalve) Close() {}
 
+func (v *valveimpl) String() string {
+	select {
+	case <-v.done:
+		return fmt.Sprintf("valve#%d closed", v.id)
+	default:
+		return fmt.Sprintf("valve#%d open", v.id)
+	}
+}
+
+func (v *nilvalve) String() string {
+	return "nil valve"
+}
+
 func (valve *valveimpl) Send(v reflect.Value) bool {
 	select {
 	case valve.ch <- v:
@@ -111,6 +148,10 @@ func (valve *valveimpl) Rchan() chan reflect.Value {
 type Pipe interface {
 	Run(*sync.WaitGroup)
 	NotifyExit()
+	//SetLogger sets Logger used by goroutines of the pipe
+	SetLogger(logging.Logger)
+	//SetContext sets context of the pipe. when it's done, valves of the pipe are closed
+	SetContext(context.Context)
 	gc.GcThing
 }
 
@@ -180,9 +221,7 @@ type connectedFF struct {
 }
 
 func ConnectPC(p Producer, c Consumer) Terminal {
-	log.Println("ConnectPC")
 	p.AddW(c.NewR())
-	log.Println("ConnectPC ok")
 	//p.Incref()
 	//c.Incref()
 	ret := &connectedPC{P: p, C: c}
@@ -198,7 +237,6 @@ func ConnectPC(p Producer, c Consumer) Terminal {
 }
 
 func ConnectPF(p Producer, f Filter) Producer {
-	log.Println("ConnectPF")
 	p.AddW(f.NewR())
 	//p.Incref()
 	//f.Incref()
@@ -215,7 +253,6 @@ func ConnectPF(p Producer, f Filter) Producer {
 }
 
 func ConnectFC(f Filter, c Consumer) Consumer {
-	log.Println("ConnectFC")
 	f.AddW(c.NewR())
 	//f.Incref()
 	//c.Incref()
@@ -233,7 +270,6 @@ func ConnectFC(f Filter, c Consumer) Consumer {
 }
 
 func ConnectFF(f1 Filter, f2 Filter) Filter {
-	log.Println("ConnectFF")
 	f1.AddW(f2.NewR())
 	//f1.Incref()
 	//f2.Incref()
@@ -1,10 +1,10 @@
 package pipe
 
 import (
-	"log"
 	"sync"
 
 	"../gc"
+	"../logging"
 )
 
 type producerChan struct {
@@ -16,6 +16,7 @@ type producerChan struct {
 	runonce    sync.Once
 	exitonce   sync.Once
 	wsmutex    sync.Mutex
+	stage      *stage
 	gc.Ref
 }
 
@@ -27,11 +28,11 @@ func NewProducer(Out Valve) Producer {
 		newW:       make(chan Valve),
 		exitnotify: make(chan bool, 1),
 	}
+	p.stage = newStage("producer", &p.Ref)
+	p.stage.input(Out)
+	p.OnRelease(p.stage.release)
+	p.OnRelease(p.NotifyExit)
 	p.Incref()
-	go func() {
-		p.Wait()
-		p.NotifyExit()
-	}()
 	return p
 }
 
@@ -45,18 +46,20 @@ func (p *producerChan) Run(wg *sync.WaitGroup) {
 	wg.Add(1)
 	p.runonce.Do(func() {
 		p.wg.Add(1)
-		log.Println("producer run")
+		p.stage.run()
+		p.stage.debug("run")
+		p.stage.watch(p.origin.Close)
 		go func() {
 			defer func() {
 				p.wsmutex.Lock()
-				log.Println("producer end", p.ws)
+				p.stage.debug("end", logging.F("receivers", len(p.ws)))
 				for _, valve := range p.ws {
 					valve.Send(EOF)
 				}
 				p.ws = nil
 				p.wsmutex.Unlock()
+				p.stage.end()
 				p.wg.Done()
-				log.Println("producer return")
 			}()
 			exitable := false
 			rchan := p.origin.Rchan()
@@ -74,7 +77,7 @@ func (p *producerChan) Run(wg *sync.WaitGroup) {
 									valids = append(valids, valve)
 								}
 							}
-							log.Println("Producer sent ", value.Interface(), len(valids))
+							p.stage.debug("sent", logging.F("value", value), logging.F("receivers", len(valids)))
 							p.ws = valids
 							if exitable && len(p.ws) == 0 {
 								p.origin.Close()
@@ -87,7 +90,7 @@ func (p *producerChan) Run(wg *sync.WaitGroup) {
 						return
 					}
 				case <-p.exitnotify:
-					log.Println("producer exit notify")
+					p.stage.debug("exit notify")
 					exitable = true
 					p.wsmutex.Lock()
 					if len(p.ws) == 0 {
@@ -110,5 +113,6 @@ func (p *producerChan) Run(wg *sync.WaitGroup) {
 func (p *producerChan) AddW(newv Valve) {
 	p.wsmutex.Lock()
 	defer p.wsmutex.Unlock()
+	p.stage.output(newv)
 	p.ws = append(p.ws, newv)
 }
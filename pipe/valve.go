@@ -0,0 +1,223 @@
+package pipe
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+//Policy decides what Valve does when its buffer is full
+type Policy int
+
+const (
+	//Block blocks sender until buffer has space
+	Block Policy = iota
+	//DropOldest drops the oldest value in buffer
+	DropOldest
+	//DropNewest drops the value being sent
+	DropNewest
+	//Fail calls overflow handler and closes Valve
+	Fail
+)
+
+var policynames = map[Policy]string{
+	Block:      "block",
+	DropOldest: "drop_oldest",
+	DropNewest: "drop_newest",
+	Fail:       "error",
+}
+
+func (p Policy) String() string {
+	return policynames[p]
+}
+
+//ParsePolicy converts name of policy. eg "drop_oldest"
+func ParsePolicy(name string) (Policy, error) {
+	for p, n := range policynames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return Block, fmt.Errorf("unknown policy %s. it must be block, drop_oldest, drop_newest or error", name)
+}
+
+//bufferedvalve is Valve which has bounded buffer.
+//EOF is never dropped and doesn't count for capacity.
+type bufferedvalve struct {
+	id        int64
+	queue     []reflect.Value
+	values    int
+	capacity  int
+	policy    Policy
+	overflow  func()
+	dropped   int
+	notempty  chan struct{}
+	notfull   chan struct{}
+	done      chan bool
+	closeonce sync.Once
+	failonce  sync.Once
+	mutex     sync.Mutex
+}
+
+//NewBufferedValve creates Valve which buffers up to capacity values.
+//overflow is called once when buffer is full and policy is Fail. it can be nil.
+func NewBufferedValve(capacity int, policy Policy, overflow func()) Valve {
+	if capacity <= 0 {
+		return NewValve()
+	}
+	return &bufferedvalve{
+		id:       nextValveID(),
+		queue:    []reflect.Value{},
+		capacity: capacity,
+		policy:   policy,
+		overflow: overflow,
+		notempty: make(chan struct{}, 1),
+		notfull:  make(chan struct{}, 1),
+		done:     make(chan bool),
+	}
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+func (valve *bufferedvalve) closed() bool {
+	select {
+	case <-valve.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (valve *bufferedvalve) String() string {
+	valve.mutex.Lock()
+	defer valve.mutex.Unlock()
+	state := "open"
+	if valve.closed() {
+		state = "closed"
+	}
+	return fmt.Sprintf("valve#%d %s, %d/%d buffered, %d dropped", valve.id, state, valve.values, valve.capacity, valve.dropped)
+}
+
+func (valve *bufferedvalve) Close() {
+	valve.closeonce.Do(func() {
+		close(valve.done)
+	})
+}
+
+//push appends v. mutex must be locked
+func (valve *bufferedvalve) push(v reflect.Value) {
+	valve.queue = append(valve.queue, v)
+	if !IsEOF(v) {
+		valve.values++
+	}
+	if valve.values < valve.capacity {
+		signal(valve.notfull)
+	}
+	signal(valve.notempty)
+}
+
+//dropOldest removes the oldest value except EOF. mutex must be locked
+func (valve *bufferedvalve) dropOldest() {
+	for i, v := range valve.queue {
+		if !IsEOF(v) {
+			valve.queue = append(valve.queue[:i], valve.queue[i+1:]...)
+			valve.values--
+			valve.dropped++
+			return
+		}
+	}
+}
+
+func (valve *bufferedvalve) Send(v reflect.Value) bool {
+	for {
+		valve.mutex.Lock()
+		if valve.closed() {
+			valve.mutex.Unlock()
+			return false
+		}
+		if IsEOF(v) || valve.values < valve.capacity {
+			valve.push(v)
+			valve.mutex.Unlock()
+			return true
+		}
+		switch valve.policy {
+		case DropNewest:
+			valve.dropped++
+			valve.mutex.Unlock()
+			return true
+		case DropOldest:
+			valve.dropOldest()
+			valve.push(v)
+			valve.mutex.Unlock()
+			return true
+		case Fail:
+			valve.mutex.Unlock()
+			//overflow is reported before readers see the end
+			valve.failonce.Do(func() {
+				if valve.overflow != nil {
+					valve.overflow()
+				}
+			})
+			valve.Close()
+			return false
+		}
+		valve.mutex.Unlock()
+		select {
+		case <-valve.notfull:
+		case <-valve.done:
+			return false
+		}
+	}
+}
+
+//Receive returns buffered values even after closed
+func (valve *bufferedvalve) Receive() (reflect.Value, bool) {
+	for {
+		valve.mutex.Lock()
+		if len(valve.queue) > 0 {
+			v := valve.queue[0]
+			valve.queue[0] = EOF
+			valve.queue = valve.queue[1:]
+			if !IsEOF(v) {
+				valve.values--
+			}
+			if len(valve.queue) > 0 {
+				signal(valve.notempty)
+			}
+			signal(valve.notfull)
+			valve.mutex.Unlock()
+			return v, true
+		}
+		valve.mutex.Unlock()
+		select {
+		case <-valve.notempty:
+		case <-valve.done:
+			valve.mutex.Lock()
+			empty := len(valve.queue) == 0
+			valve.mutex.Unlock()
+			if empty {
+				return EOF, false
+			}
+		}
+	}
+}
+
+func (valve *bufferedvalve) Rchan() chan reflect.Value {
+	r := make(chan reflect.Value, 1)
+	go func() {
+		defer close(r)
+		for {
+			v, ok := valve.Receive()
+			if !ok {
+				return
+			}
+			r <- v
+		}
+	}()
+	return r
+}
@@ -0,0 +1,172 @@
+package pipe
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func receiveAll(v Valve, t *testing.T) []int {
+	ret := []int{}
+	for {
+		value, ok := v.Receive()
+		if !ok || IsEOF(value) {
+			return ret
+		}
+		ret = append(ret, value.Interface().(int))
+	}
+}
+
+func assertInts(got []int, expected []int, t *testing.T) {
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %v expected %v", got, expected)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, p := range []Policy{Block, DropOldest, DropNewest, Fail} {
+		if parsed, err := ParsePolicy(p.String()); err != nil || parsed != p {
+			t.Errorf("ParsePolicy(%q) got %v %v", p.String(), parsed, err)
+		}
+	}
+	if _, err := ParsePolicy("drop"); err == nil {
+		t.Errorf("unknown policy must be error")
+	}
+}
+
+func TestDropNewest(t *testing.T) {
+	v := NewBufferedValve(3, DropNewest, nil)
+	for i := 0; i < 10; i++ {
+		if !v.Send(reflect.ValueOf(i)) {
+			t.Fatalf("Send must not fail")
+		}
+	}
+	v.Send(EOF)
+	assertInts(receiveAll(v, t), []int{0, 1, 2}, t)
+}
+
+func TestDropOldest(t *testing.T) {
+	v := NewBufferedValve(3, DropOldest, nil)
+	for i := 0; i < 10; i++ {
+		v.Send(reflect.ValueOf(i))
+	}
+	v.Send(EOF)
+	assertInts(receiveAll(v, t), []int{7, 8, 9}, t)
+}
+
+func TestDropKeepsEOF(t *testing.T) {
+	v := NewBufferedValve(2, DropOldest, nil)
+	v.Send(reflect.ValueOf(1))
+	v.Send(EOF)
+	v.Send(reflect.ValueOf(2))
+	v.Send(reflect.ValueOf(3))
+	if value, _ := v.Receive(); !IsEOF(value) {
+		t.Errorf("EOF must not be dropped. got %v", value)
+	}
+	v.Close()
+	assertInts(receiveAll(v, t), []int{2, 3}, t)
+}
+
+func TestFail(t *testing.T) {
+	overflowed := 0
+	v := NewBufferedValve(2, Fail, func() { overflowed++ })
+	v.Send(reflect.ValueOf(1))
+	v.Send(reflect.ValueOf(2))
+	if v.Send(reflect.ValueOf(3)) {
+		t.Errorf("Send must fail when buffer is full")
+	}
+	if overflowed != 1 {
+		t.Errorf("overflow handler called %d times", overflowed)
+	}
+	if v.Send(reflect.ValueOf(4)) {
+		t.Errorf("Send must fail after overflow")
+	}
+	assertInts(receiveAll(v, t), []int{1, 2}, t)
+}
+
+func TestBlock(t *testing.T) {
+	v := NewBufferedValve(2, Block, nil)
+	v.Send(reflect.ValueOf(1))
+	v.Send(reflect.ValueOf(2))
+	sent := make(chan bool)
+	go func() {
+		sent <- v.Send(reflect.ValueOf(3))
+	}()
+	select {
+	case <-sent:
+		t.Fatalf("Send must block when buffer is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if value, _ := v.Receive(); value.Interface().(int) != 1 {
+		t.Errorf("unexpected %v", value)
+	}
+	if !<-sent {
+		t.Errorf("blocked Send must succeed after Receive")
+	}
+	v.Close()
+	assertInts(receiveAll(v, t), []int{2, 3}, t)
+}
+
+func TestBlockedSendIsReleasedByClose(t *testing.T) {
+	v := NewBufferedValve(1, Block, nil)
+	v.Send(reflect.ValueOf(1))
+	sent := make(chan bool)
+	go func() {
+		sent <- v.Send(reflect.ValueOf(2))
+	}()
+	v.Close()
+	if <-sent {
+		t.Errorf("Send must fail after Close")
+	}
+}
+
+func TestBufferIsBounded(t *testing.T) {
+	for _, p := range []Policy{DropOldest, DropNewest} {
+		v := NewBufferedValve(100, p, nil).(*bufferedvalve)
+		done := make(chan bool)
+		go func() {
+			defer close(done)
+			for i := 0; i < 100000; i++ {
+				v.Send(reflect.ValueOf(i))
+				if i%1000 == 0 {
+					v.mutex.Lock()
+					if len(v.queue) > 100 {
+						t.Errorf("%s: buffer grows to %d", p, len(v.queue))
+					}
+					v.mutex.Unlock()
+				}
+			}
+		}()
+		<-done
+		if v.dropped != 100000-100 {
+			t.Errorf("%s: dropped %d", p, v.dropped)
+		}
+	}
+}
+
+func TestBufferedChan(t *testing.T) {
+	c := NewBufferedChan(2, DropNewest, nil)
+	r := c.NewR()
+	for i := 0; i < 5; i++ {
+		r.Send(reflect.ValueOf(i))
+	}
+	r.Send(EOF)
+	assertInts(receiveAll(r, t), []int{0, 1}, t)
+}
+
+func TestContextValve(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	v := NewContextValve(ctx)
+	sent := make(chan bool)
+	go func() {
+		sent <- v.Send(reflect.ValueOf(1))
+	}()
+	cancel()
+	if <-sent {
+		t.Errorf("Send must fail after cancel")
+	}
+	if _, ok := v.Receive(); ok {
+		t.Errorf("Receive must return EOF after cancel")
+	}
+}
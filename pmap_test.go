@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestPmapOrdered(t *testing.T) {
+	assertNum(`xs = seq(20) | pmap({x -> x * x},4,true) | collect();xs[0] + xs[19]`, "401", t)
+}
+
+func TestPmapUnordered(t *testing.T) {
+	assertNum(`xs = seq(20) | pmap({x -> if x % 2 == 0 {skip}; x},3) | collect();len(xs)`, "10", t)
+}
+
+func TestPmapEmit(t *testing.T) {
+	assertNum(`xs = seq(3) | pmap({x -> emit x; x * 10},2,true) | collect();xs[4] + xs[5]`, "33", t)
+}
+
+func TestPmapClose(t *testing.T) {
+	assertNum(`xs = seq(100) | pmap({x -> if x == 5 {close} else {x}},2,true) | collect();len(xs)`, "4", t)
+}
+
+func TestPmapNotFunction(t *testing.T) {
+	assertError(`seq(3) | pmap(nil) | STDOUT`, "Error: nil is not function", t)
+	assertError(`seq(3) | pmap({x -> x},2,nil) | STDOUT`, "Error: nil is not bool", t)
+}
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestRecoverSkip(t *testing.T) {
+	assertNum(`xs = [1,"a",3] | recover({x -> x * 2},{e -> skip}) | collect();xs[0]+xs[1]`, "8", t)
+}
+
+func TestRecoverReplace(t *testing.T) {
+	assertNum(`xs = [1,"a",3] | recover({x -> x * 2},{e,x -> 100}) | collect();xs[1]`, "100", t)
+}
+
+func TestRecoverClose(t *testing.T) {
+	assertNum(`x = [1,"a",3] | recover({x -> x * 2},{e -> close}) | last();x+0`, "2", t)
+}
+
+func TestRecoverNotFunction(t *testing.T) {
+	assertError(`recover(nil,nil)`, "nil is not function", t)
+	assertError(`recover({x -> x},1)`, "1 is not function", t)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"./parser"
+	"./pipe"
+	"./vm"
+)
+
+const (
+	prompt         = "> "
+	continuePrompt = "... "
+	historyFile    = ".nstrm_history"
+)
+
+//history keeps entries of repl and saves them to file if it's given
+type history struct {
+	entries []string
+	file    *os.File
+}
+
+func openHistory() *history {
+	h := &history{entries: []string{}}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h
+	}
+	path := filepath.Join(home, historyFile)
+	if buffer, err := ioutil.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(buffer), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, unescapeEntry(line))
+			}
+		}
+	}
+	if f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600); err == nil {
+		h.file = f
+	}
+	return h
+}
+
+func (h *history) add(entry string) {
+	h.entries = append(h.entries, entry)
+	if h.file != nil {
+		fmt.Fprintln(h.file, escapeEntry(entry))
+	}
+}
+
+//escapeEntry converts entry to one line of history file. newline is saved as \n and backslash as \\
+func escapeEntry(entry string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(entry)
+}
+
+//unescapeEntry restores entry saved by escapeEntry
+func unescapeEntry(line string) string {
+	var buf strings.Builder
+	escaped := false
+	for _, c := range line {
+		switch {
+		case escaped && c == 'n':
+			buf.WriteRune('\n')
+			escaped = false
+		case escaped:
+			buf.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		default:
+			buf.WriteRune(c)
+		}
+	}
+	return buf.String()
+}
+
+func (h *history) close() {
+	if h.file != nil {
+		h.file.Close()
+	}
+}
+
+//depth counts unclosed brackets in src. strings and comments are ignored.
+func depth(src string) int {
+	d := 0
+	instring := false
+	escaped := false
+	incomment := false
+	for _, c := range src {
+		switch {
+		case incomment:
+			if c == '\n' {
+				incomment = false
+			}
+		case escaped:
+			escaped = false
+		case instring:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				instring = false
+			}
+		case c == '"':
+			instring = true
+		case c == '#':
+			incomment = true
+		case c == '{' || c == '[' || c == '(':
+			d++
+		case c == '}' || c == ']' || c == ')':
+			d--
+		}
+	}
+	return d
+}
+
+//inspect formats value for repl output. result of pipe is shown instead of pipe itself.
+func inspect(v reflect.Value) string {
+	if v.IsValid() {
+		if t, ok := v.Interface().(pipe.Terminal); ok {
+			if ret := t.Result(); ret.IsValid() {
+				return vm.Inspect(ret)
+			}
+			return ""
+		}
+	}
+	return vm.Inspect(v)
+}
+
+//eval runs one entry of repl in env and returns formatted result.
+//error occurred in pipe is also returned as error.
+func eval(src string, env *vm.Env) (string, error) {
+	p := &parser.Nstrm{Buffer: src}
+	p.Init()
+	p.MyParser.Init()
+	if err := p.Parse(); err != nil {
+		return "", err
+	}
+	p.Execute()
+	var pipeerr *vm.Error
+	var pipeerrmutex sync.Mutex
+	env.SetErrorHandler(func(E *vm.Error) {
+		pipeerrmutex.Lock()
+		defer pipeerrmutex.Unlock()
+		if pipeerr == nil {
+			pipeerr = E
+		}
+	})
+	ret, err := p.RunPart(env)
+	env.Flush()
+	if err == nil && pipeerr != nil {
+		err = pipeerr
+	}
+	if err != nil {
+		switch E := err.(type) {
+		case *vm.Error:
+			return "", fmt.Errorf("%s", E.Show(src))
+		default:
+			return "", fmt.Errorf("unexpected %T at toplevel", E)
+		}
+	}
+	return inspect(ret), nil
+}
+
+//repl reads entries from in and evaluates them in env until EOF
+func repl(env *vm.Env, in io.Reader, out io.Writer, h *history) {
+	scanner := bufio.NewScanner(in)
+	lines := []string{}
+	fmt.Fprint(out, prompt)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		src := strings.Join(lines, "\n")
+		if depth(src) > 0 {
+			fmt.Fprint(out, continuePrompt)
+			continue
+		}
+		lines = lines[:0]
+		switch strings.TrimSpace(src) {
+		case "":
+		case ":quit", ":q":
+			return
+		case ":history":
+			for i, entry := range h.entries {
+				fmt.Fprintf(out, "%d: %s\n", i+1, entry)
+			}
+		default:
+			h.add(src)
+			if result, err := eval(src, env); err == nil {
+				if result != "" {
+					fmt.Fprintln(out, result)
+				}
+			} else {
+				fmt.Fprintln(out, err)
+			}
+		}
+		fmt.Fprint(out, prompt)
+	}
+}
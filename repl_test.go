@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./vm"
+)
+
+func TestDepth(t *testing.T) {
+	cases := map[string]int{
+		"1+1":              0,
+		"{x ->":            1,
+		"[1, (2":           2,
+		"\"{\" # [":        0,
+		"{x -> [x]}":       0,
+		"f = {x ->\n x\n}": 0,
+		`"a\"{"`:           0,
+		`"a\\" + {`:        1,
+	}
+	for src, expected := range cases {
+		if d := depth(src); d != expected {
+			t.Errorf("depth(%q) got %d expected %d", src, d, expected)
+		}
+	}
+}
+
+func TestHistoryEscape(t *testing.T) {
+	for _, entry := range []string{"1+1", "f = {x ->\n x\n}", `"a\nb"`, `"a\\nb"`, "\\"} {
+		line := escapeEntry(entry)
+		if strings.Contains(line, "\n") {
+			t.Errorf("escapeEntry(%q) has newline", entry)
+		}
+		if got := unescapeEntry(line); got != entry {
+			t.Errorf("round trip of %q got %q", entry, got)
+		}
+	}
+}
+
+func TestREPL(t *testing.T) {
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	in := strings.NewReader("a = 10\nf = {x ->\n  x * a\n}\nf(4)\nseq(3) | collect()\nb\n")
+	var out bytes.Buffer
+	repl(env, in, &out, &history{})
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+
+	got := out.String()
+	for _, expected := range []string{"> 10\n", "> ... ... <function>\n", "> 40\n", "> [1, 2, 3]\n", "b is undefined"} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("%q is not in output %q", expected, got)
+		}
+	}
+}
+
+func TestREPLStdin(t *testing.T) {
+	stdin := builtins.Stdin
+	builtins.Stdin = nil
+	defer func() {
+		builtins.Stdin = stdin
+	}()
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	in := strings.NewReader("STDIN | take(2) | collect()\n5+5\n")
+	var out bytes.Buffer
+	repl(env, in, &out, &history{})
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+
+	//STDIN is empty and the next line is left to REPL
+	if got := out.String(); !strings.Contains(got, "> []\n> 10\n") {
+		t.Errorf("unexpected output %q", got)
+	}
+}
+
+func TestEvalPipeError(t *testing.T) {
+	var wg sync.WaitGroup
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	if _, err := eval("[1,2] | {x -> y} | STDOUT", env); err == nil || !strings.Contains(err.Error(), "y is undefined") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if got, err := eval("1+1", env); err != nil || got != "2" {
+		t.Errorf("env must be usable after error. got %q %v", got, err)
+	}
+	env.RunWait(vm.NIL)
+	env.Decref()
+	wg.Wait()
+}
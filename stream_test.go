@@ -0,0 +1,65 @@
+package main
+
+import "testing"
+
+func TestBatch(t *testing.T) {
+	assertNum(`xs = seq(7) | batch(3) | collect();len(xs) * 10 + len(xs[2])`, "31", t)
+}
+
+func TestSliding(t *testing.T) {
+	assertNum(`xs = seq(5) | sliding(3) | {w -> w[0] + w[2]} | collect();xs[0] + xs[2]`, "12", t)
+}
+
+func TestSlidingShort(t *testing.T) {
+	assertNum(`xs = seq(2) | sliding(3) | collect();len(xs)`, "0", t)
+}
+
+func TestWindow(t *testing.T) {
+	assertNum(`xs = seq(10) | window(1000) | collect();len(xs[0])`, "10", t)
+	//values come every 300ms. each of them is in its own window
+	assertNum(`xs = interval(300) | take(2) | window(100) | collect();len(xs) * 10 + len(xs[1])`, "21", t)
+	//pipes are evaluated before they are buffered
+	assertStr(`xs = [1,seq(3) | sum()] | window(1000) | collect();str(xs[0])`, "[1, 6]", t)
+}
+
+func TestTakeDrop(t *testing.T) {
+	assertNum(`xs = seq(10) | drop(2) | take(3) | collect();len(xs) * 10 + xs[0]`, "33", t)
+}
+
+func TestTakeWhile(t *testing.T) {
+	assertNum(`xs = seq(10) | takewhile({x -> x < 4}) | collect();len(xs)`, "3", t)
+}
+
+func TestDropWhile(t *testing.T) {
+	assertNum(`xs = seq(10) | dropwhile({x -> x < 4}) | collect();xs[0]`, "4", t)
+}
+
+func TestFold(t *testing.T) {
+	assertNum(`x = seq(5) | fold(MUL,1);x+0`, "120", t)
+	assertNum(`x = seq(5) | fold({acc,v -> acc + v * v},0);x+0`, "55", t)
+	assertNum(`x = seq(10) | sum();x+0`, "55", t)
+	assertNum(`x = seq(10) | count();x+0`, "10", t)
+}
+
+func TestMinMax(t *testing.T) {
+	assertNum(`x = [3,1,2] | min();y = [3,1,2] | max();x*10+y`, "13", t)
+}
+
+func TestZip(t *testing.T) {
+	assertNum(`xs = zip(seq(3),seq(5)) | collect();len(xs) * 10 + xs[2][1]`, "33", t)
+}
+
+func TestMergeConcat(t *testing.T) {
+	assertNum(`x = merge(seq(3),seq(4)) | sum();x+0`, "16", t)
+	assertNum(`xs = concat(seq(2),seq(3)) | collect();len(xs) * 10 + xs[2]`, "51", t)
+	//producer which ends with filter and combinators called in function
+	assertNum(`xs = concat([1,2],seq(3) | take(1) |) | collect();len(xs) * 10 + xs[2]`, "31", t)
+	assertNum(`f = {n -> zip(seq(n),seq(n) | {x -> x * 10} |) | collect()};xs = f(3);len(xs) * 100 + xs[2][1]`, "330", t)
+}
+
+func TestNotFunction(t *testing.T) {
+	assertError(`[1] | takewhile(nil) | STDOUT`, "line: 1, Column: 6", t)
+	assertError(`[1] | dropwhile(nil) | STDOUT`, "line: 1, Column: 6", t)
+	assertError(`[1] | fold(nil,0) | STDOUT`, "line: 1, Column: 6", t)
+	assertError(`[1] | fold(nil,0) | STDOUT`, "Error: nil is not function", t)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"./builtins"
+	"./parser"
+	"./vm"
+)
+
+func assertStr(expr string, expected string, t *testing.T) {
+	var wg sync.WaitGroup
+	p := &parser.Nstrm{Buffer: expr}
+	p.Init()
+	p.MyParser.Init()
+	if err := p.Parse(); err != nil {
+		t.Errorf("Parser Error")
+		return
+	}
+	p.Execute()
+	env := vm.NewEnv(&wg)
+	builtins.LoadCore(env)
+	if v, err := p.Run(env); err == nil {
+		if s, ok := vm.Eval(v).Interface().(string); !ok || s != expected {
+			t.Errorf("unexpected return got %v expected %q", vm.Inspect(v), expected)
+		}
+	} else {
+		t.Fatal(err)
+	}
+}
+
+func TestStringEscape(t *testing.T) {
+	assertStr(`"a\"b\\c\n\$x"`, "a\"b\\c\n$x", t)
+}
+
+func TestStringInterp(t *testing.T) {
+	assertStr(`x = 2;"x=${x} y=${x * 3} a=${[x,"s"]}"`, `x=2 y=6 a=[2, "s"]`, t)
+}
+
+func TestStringInterpNested(t *testing.T) {
+	assertStr(`f = {x -> "<${x}>"};"${f("${f(1)}")}"`, "<<1>>", t)
+}
+
+func TestStringConcat(t *testing.T) {
+	assertStr(`"a" + "b" + 1`, "ab1", t)
+}
+
+func TestStringFunctions(t *testing.T) {
+	assertStr(`join(split(" a,b ,c ",","),"-")`, " a-b -c ", t)
+	assertStr(`trim("  x ") + lower("ABC") + upper("d")`, "xabcD", t)
+	assertStr(`replace("a.b.c",".","/")`, "a/b/c", t)
+	assertStr(`substr("hello",1,3) + substr("hello",4)`, "ello", t)
+	assertStr(`gsub("a1b22","[0-9]+","<$0>")`, "a<1>b<22>", t)
+	assertStr(`match("key=val","(\\w+)=(\\w+)")[2]`, "val", t)
+	assertStr(`str(12)`, "12", t)
+}
+
+func TestStringPredicates(t *testing.T) {
+	assertNum(`if contains("abc","bc") && startswith("abc","a") && contains([1,2],3) == false {1} else {0}`, "1", t)
+	assertNum(`len("abc") + len([1]) + len({a: 1})`, "5", t)
+	assertNum(`num("41") + 1`, "42", t)
+	//rational isn't truncated to integer
+	assertStr(`str(num("1/3"))`, "1/3", t)
+	assertStr(`str(num("1e3"))`, "1000", t)
+}
+
+func TestStringArgs(t *testing.T) {
+	assertError(`upper()`, "wrong number of argments", t)
+	assertError(`upper("a","b")`, "wrong number of argments", t)
+	assertStr(`str(upper(nil))`, "nil", t)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestInterval(t *testing.T) {
+	assertNum(`xs = interval(1) | take(3) | collect();xs[0] * 10 + xs[2]`, "2", t)
+}
+
+func TestAfter(t *testing.T) {
+	assertNum(`start = now();xs = after(20) | {t -> if t - start >= 20 {t}} | collect();len(xs)`, "1", t)
+}
+
+func TestTimeout(t *testing.T) {
+	assertNum(`xs = interval(1) | timeout(1000) | take(2) | collect();len(xs)`, "2", t)
+	assertNum(`xs = after(500) | timeout(10) | collect();len(xs)`, "0", t)
+}
+
+func TestThrottle(t *testing.T) {
+	assertNum(`start = now();xs = seq(4) | throttle(20) | collect();n = len(xs);ys = [now() - start] | {d -> if d >= 100 {d}} | collect();n * 10 + len(ys)`, "41", t)
+}
+
+func TestDebounce(t *testing.T) {
+	assertNum(`xs = seq(1000) | debounce(20) | collect();xs[0] * 10 + len(xs)`, "10001", t)
+}
+
+func TestSample(t *testing.T) {
+	assertNum(`xs = interval(2) | take(20) | sample(1000) | collect();xs[0] * 10 + len(xs)`, "191", t)
+}
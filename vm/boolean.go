@@ -1,7 +1,6 @@
 package vm
 
 import (
-	"log"
 	"reflect"
 	"sync"
 
@@ -18,38 +17,41 @@ func Condition(v reflect.Value) bool {
 		return t
 	case pipe.Terminal:
 		var wg sync.WaitGroup
-		log.Println("<<<<<")
 		t.Run(&wg)
 		t.NotifyExit()
 		wg.Wait()
 		ret := t.Result()
-		log.Println(">>>>>")
 		return Condition(ret)
 	}
 	panic("cant be condition")
 }
 
 func Equal(a, b reflect.Value) bool {
-	if cmp, err := CmpV(a, b); err == nil {
-		return cmp == 0
-	}
 	if !a.IsValid() || !b.IsValid() {
 		return !a.IsValid() && !b.IsValid()
 	}
-	switch arr1 := a.Interface().(type) {
+	if cmp, err := CmpV(a, b); err == nil {
+		return cmp == 0
+	}
+	switch l := a.Interface().(type) {
 	case []reflect.Value:
-		switch arr2 := b.Interface().(type) {
+		switch r := b.Interface().(type) {
 		case []reflect.Value:
-			if len(arr1) != len(arr2) {
+			if len(l) != len(r) {
 				return false
 			}
-			for i := 0; i < len(arr1); i++ {
-				if !Equal(arr1[i], arr2[i]) {
+			for i := 0; i < len(l); i++ {
+				if !Equal(l[i], r[i]) {
 					return false
 				}
 			}
 			return true
 		}
+	case *Map:
+		switch r := b.Interface().(type) {
+		case *Map:
+			return l.Equal(r)
+		}
 	}
 	return a.Interface() == b.Interface()
 }
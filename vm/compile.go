@@ -0,0 +1,321 @@
+package vm
+
+import (
+	"reflect"
+
+	"../ast"
+)
+
+//opcode is kind of instruction
+type opcode byte
+
+const (
+	opConst       opcode = iota //push consts[a]
+	opPop                       //discard top
+	opLoad                      //push slot b of Env at depth a. exprs[c] is for error
+	opLoadName                  //push names[b] looked up from Env at depth a. exprs[c] is for error
+	opStore                     //bind top to slot b of Env at depth a
+	opStoreName                 //define names[b] in Env at depth a
+	opLoadGlobal                //push cells[b] of Env at depth a. exprs[c] is for error
+	opStoreGlobal               //bind top to cells[b] of Env at depth a
+	opCall                      //call function under a argments. exprs[b] is Funcall
+	opPipe                      //connect a values. exprs[b] is Pipe
+	opBlock                     //push function of codes[a]. exprs[b] is Block
+	opJump                      //jump to a
+	opJumpIfNot                 //pop and jump to a unless it's true
+	opWhile                     //loop with condition codes[a] and body codes[a+1]. exprs[b] is While
+	opArray                     //make array of a values
+	opMap                       //make map of a pairs. exprs[b] is Map
+	opInterp                    //concatenate a values to string
+	opIndex                     //exprs[b] is Index
+	opSlice                     //exprs[b] is Slice
+	opEmit                      //pop and send to out. exprs[b] is the element
+	opSkip                      //skip
+	opClose                     //close. return top if a is 1
+	opImport                    //exprs[b] is Import
+	opWait                      //wait
+)
+
+//instr is instruction of Code
+type instr struct {
+	op      opcode
+	a, b, c int
+}
+
+//Code is compiled expressions executed by Exec.
+//variables bound in blocks and while loops are resolved to slots of Env.
+//variables at top level are kept in namespace so that they can be accessed by name, and Code refers them as cells.
+type Code struct {
+	ops    []instr
+	consts []reflect.Value
+	names  []string
+	exprs  []ast.Expr
+	codes  []*Code
+	cells  []*slot
+	nslots int
+}
+
+//compiler builds one Code
+type compiler struct {
+	code  *Code
+	env   *Env
+	names map[string]int
+	cells map[*slot]int
+}
+
+//Compile resolves and compiles expressions at top level of env.
+//env is used to know variables defined before and to bind variables of top level, and can be nil.
+//error for undefined variable is returned before execution.
+func Compile(exprs []ast.Expr, env *Env) (*Code, *Error) {
+	if errs := Resolve(exprs, env); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return compileIn(exprs, 0, env)
+}
+
+//CompileResolved compiles expressions which are already resolved by Resolve in env
+func CompileResolved(exprs []ast.Expr, env *Env) (*Code, *Error) {
+	return compileIn(exprs, 0, env)
+}
+
+//CompilePart compiles expressions which are a part of program run in env one after another, such as lines of REPL.
+//variables which are not bound yet are bound and looked up by name at runtime, because later part may define them.
+func CompilePart(exprs []ast.Expr, env *Env) (*Code, *Error) {
+	if errs := resolve(exprs, env, true); len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return compileIn(exprs, 0, env)
+}
+
+func compileIn(exprs []ast.Expr, nslots int, env *Env) (*Code, *Error) {
+	c := &compiler{
+		code:  &Code{nslots: nslots},
+		env:   env,
+		names: make(map[string]int),
+		cells: make(map[*slot]int),
+	}
+	if err := c.list(exprs); err != nil {
+		return nil, err
+	}
+	return c.code, nil
+}
+
+func (c *compiler) emit(op opcode, a, b, c2 int) int {
+	c.code.ops = append(c.code.ops, instr{op: op, a: a, b: b, c: c2})
+	return len(c.code.ops) - 1
+}
+
+func (c *compiler) constant(v reflect.Value) int {
+	c.code.consts = append(c.code.consts, v)
+	return len(c.code.consts) - 1
+}
+
+func (c *compiler) name(name string) int {
+	if i, ok := c.names[name]; ok {
+		return i
+	}
+	c.code.names = append(c.code.names, name)
+	c.names[name] = len(c.code.names) - 1
+	return len(c.code.names) - 1
+}
+
+func (c *compiler) expr(e ast.Expr) int {
+	c.code.exprs = append(c.code.exprs, e)
+	return len(c.code.exprs) - 1
+}
+
+//list compiles exprs which leave the value of the last one
+func (c *compiler) list(exprs []ast.Expr) *Error {
+	if len(exprs) == 0 {
+		c.emit(opConst, c.constant(NIL), 0, 0)
+		return nil
+	}
+	for i, e := range exprs {
+		if i > 0 {
+			c.emit(opPop, 0, 0, 0)
+		}
+		if err := c.compile(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//cell returns index of slot of top level variable. it returns false if there is no Env
+func (c *compiler) cell(id string) (int, bool) {
+	if c.env == nil {
+		return 0, false
+	}
+	cell := c.env.cell(id)
+	if i, ok := c.cells[cell]; ok {
+		return i, true
+	}
+	c.code.cells = append(c.code.cells, cell)
+	c.cells[cell] = len(c.code.cells) - 1
+	return len(c.code.cells) - 1, true
+}
+
+func (c *compiler) load(id string, r ast.Resolved, e ast.Expr) {
+	switch r.Scope {
+	case ast.Local:
+		c.emit(opLoad, r.Depth, r.Index, c.expr(e))
+		return
+	case ast.Global:
+		if i, ok := c.cell(id); ok {
+			c.emit(opLoadGlobal, r.Depth, i, c.expr(e))
+			return
+		}
+	}
+	c.emit(opLoadName, r.Depth, c.name(id), c.expr(e))
+}
+
+func (c *compiler) store(id string, r ast.Resolved) {
+	switch r.Scope {
+	case ast.Local:
+		c.emit(opStore, r.Depth, r.Index, 0)
+		return
+	case ast.Global:
+		if i, ok := c.cell(id); ok {
+			c.emit(opStoreGlobal, r.Depth, i, 0)
+			return
+		}
+	}
+	c.emit(opStoreName, r.Depth, c.name(id), 0)
+}
+
+func (c *compiler) sub(exprs []ast.Expr, nslots int) (int, *Error) {
+	code, err := compileIn(exprs, nslots, c.env)
+	if err != nil {
+		return 0, err
+	}
+	c.code.codes = append(c.code.codes, code)
+	return len(c.code.codes) - 1, nil
+}
+
+func (c *compiler) compile(expr ast.Expr) *Error {
+	switch E := expr.(type) {
+	case *ast.Literal:
+		c.emit(opConst, c.constant(E.Value), 0, 0)
+	case *ast.BindVar:
+		if err := c.compile(E.Expr); err != nil {
+			return err
+		}
+		c.store(E.Identifer, E.Resolved)
+	case *ast.RefVar:
+		c.load(E.Identifer, E.Resolved, E)
+	case *ast.Funcall:
+		c.load(E.Identifer, E.Resolved, E)
+		for _, arg := range E.Args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(opCall, len(E.Args), c.expr(E), 0)
+	case *ast.Pipe:
+		for _, arg := range E.Args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		c.emit(opPipe, len(E.Args), c.expr(E), 0)
+	case *ast.Import:
+		c.emit(opImport, 0, c.expr(E), 0)
+	case *ast.Wait:
+		c.emit(opWait, 0, 0, 0)
+	case *ast.Block:
+		i, err := c.sub(E.Body, E.Slots)
+		if err != nil {
+			return err
+		}
+		c.emit(opBlock, i, c.expr(E), 0)
+	case *ast.If:
+		if err := c.list(E.Cond); err != nil {
+			return err
+		}
+		jelse := c.emit(opJumpIfNot, 0, 0, 0)
+		if err := c.list(E.True); err != nil {
+			return err
+		}
+		jend := c.emit(opJump, 0, 0, 0)
+		c.code.ops[jelse].a = len(c.code.ops)
+		if err := c.list(E.Else); err != nil {
+			return err
+		}
+		c.code.ops[jend].a = len(c.code.ops)
+	case *ast.While:
+		i, err := c.sub(E.Cond, E.CondSlots)
+		if err != nil {
+			return err
+		}
+		if _, err := c.sub(E.Body, E.BodySlots); err != nil {
+			return err
+		}
+		c.emit(opWhile, i, c.expr(E), 0)
+	case *ast.Array:
+		for _, el := range E.Elements {
+			if err := c.compile(el); err != nil {
+				return err
+			}
+		}
+		c.emit(opArray, len(E.Elements), 0, 0)
+	case *ast.StringInterp:
+		for _, part := range E.Parts {
+			if err := c.compile(part); err != nil {
+				return err
+			}
+		}
+		c.emit(opInterp, len(E.Parts), 0, 0)
+	case *ast.Map:
+		for i, key := range E.Keys {
+			if err := c.compile(key); err != nil {
+				return err
+			}
+			if err := c.compile(E.Values[i]); err != nil {
+				return err
+			}
+		}
+		c.emit(opMap, len(E.Keys), c.expr(E), 0)
+	case *ast.Index:
+		if err := c.compile(E.Target); err != nil {
+			return err
+		}
+		if err := c.compile(E.Index); err != nil {
+			return err
+		}
+		c.emit(opIndex, 0, c.expr(E), 0)
+	case *ast.Slice:
+		if err := c.compile(E.Target); err != nil {
+			return err
+		}
+		for _, e := range []ast.Expr{E.From, E.To} {
+			if e == nil {
+				c.emit(opConst, c.constant(NIL), 0, 0)
+			} else if err := c.compile(e); err != nil {
+				return err
+			}
+		}
+		c.emit(opSlice, 0, c.expr(E), 0)
+	case *ast.Emit:
+		for _, el := range E.Elements {
+			if err := c.compile(el); err != nil {
+				return err
+			}
+			c.emit(opEmit, 0, c.expr(el), 0)
+		}
+		c.emit(opConst, c.constant(NIL), 0, 0)
+	case *ast.Skip:
+		c.emit(opSkip, 0, 0, 0)
+	case *ast.Close:
+		if len(E.Ret) == 0 {
+			c.emit(opClose, 0, 0, 0)
+		} else {
+			if err := c.compile(E.Ret[0]); err != nil {
+				return err
+			}
+			c.emit(opClose, 1, 0, 0)
+		}
+	default:
+		return Errorf(expr, "unimplemented Expr")
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"../pipe"
+)
+
+//NewFloat creates Number from float64. it returns false for NaN and Inf
+func NewFloat(f float64) (Number, bool) {
+	r := new(big.Rat)
+	if r.SetFloat64(f) == nil {
+		return Number{}, false
+	}
+	return Number{Rat: r, isfloat: true}, true
+}
+
+//ToGo converts value of script to Go value.
+//Number is int64 unless it is float or too large, otherwise float64.
+//array is []interface{} and Map is map[interface{}]interface{}. Terminal is evaluated.
+//other values such as Function and Producer are returned as it is.
+func ToGo(v reflect.Value) interface{} {
+	v = Eval(v)
+	if !v.IsValid() {
+		return nil
+	}
+	switch t := v.Interface().(type) {
+	case Number:
+		if !t.isfloat && t.IsInt() && t.Num().IsInt64() {
+			return t.Num().Int64()
+		}
+		f, _ := t.Float64()
+		return f
+	case []reflect.Value:
+		ret := make([]interface{}, len(t))
+		for i, e := range t {
+			ret[i] = ToGo(e)
+		}
+		return ret
+	case *Map:
+		ret := make(map[interface{}]interface{}, t.Len())
+		t.Each(func(key, value reflect.Value) bool {
+			ret[ToGo(key)] = ToGo(value)
+			return true
+		})
+		return ret
+	}
+	return v.Interface()
+}
+
+//FromGo converts Go value to value of script.
+//integers and floats are Number, slices and arrays are array, maps are Map.
+//reflect.Value is returned as it is. other types such as struct, chan and pointer are error.
+func FromGo(x interface{}) (reflect.Value, error) {
+	if x == nil {
+		return NIL, nil
+	}
+	if v, ok := x.(reflect.Value); ok {
+		return v, nil
+	}
+	switch x.(type) {
+	case Number, Function, *Map, pipe.Pipe, []reflect.Value:
+		return reflect.ValueOf(x), nil
+	}
+	return fromGo(reflect.ValueOf(x))
+}
+
+func fromGo(v reflect.Value) (reflect.Value, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(NewInt(v.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n := Number{Rat: new(big.Rat).SetInt(new(big.Int).SetUint64(v.Uint()))}
+		return reflect.ValueOf(n), nil
+	case reflect.Float32, reflect.Float64:
+		n, ok := NewFloat(v.Float())
+		if !ok {
+			return NIL, fmt.Errorf("%v can't be a number", v.Float())
+		}
+		return reflect.ValueOf(n), nil
+	case reflect.String:
+		return reflect.ValueOf(v.String()), nil
+	case reflect.Bool:
+		return reflect.ValueOf(v.Bool()), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return NIL, nil
+		}
+		if v.Kind() == reflect.Interface {
+			return FromGo(v.Elem().Interface())
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NIL, nil
+		}
+		ret := make([]reflect.Value, v.Len())
+		for i := range ret {
+			e, err := FromGo(v.Index(i).Interface())
+			if err != nil {
+				return NIL, err
+			}
+			ret[i] = e
+		}
+		return reflect.ValueOf(ret), nil
+	case reflect.Map:
+		if v.IsNil() {
+			return NIL, nil
+		}
+		m := NewMap()
+		for _, key := range v.MapKeys() {
+			k, err := FromGo(key.Interface())
+			if err != nil {
+				return NIL, err
+			}
+			e, err := FromGo(v.MapIndex(key).Interface())
+			if err != nil {
+				return NIL, err
+			}
+			if m, err = m.Set(k, e); err != nil {
+				return NIL, err
+			}
+		}
+		return reflect.ValueOf(m), nil
+	}
+	return NIL, fmt.Errorf("unsupported type %s", v.Type())
+}
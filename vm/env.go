@@ -1,43 +1,74 @@
 package vm
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"reflect"
 	"sync"
 
+	"../ast"
 	"../gc"
+	"../logging"
 	"../pipe"
 )
 
 //Env is a state for run vm
 type Env struct {
 	parent          *Env
-	namespace       map[string]reflect.Value
+	root            *Env
+	onerror         func(*Error)
+	importer        Importer
+	source          *Source
+	ctx             context.Context
+	follow          *Env
+	logger          logging.Logger
+	namespace       map[string]*slot
+	slots           []slot
 	out             pipe.Valve
 	runnotify       map[pipe.Pipe]bool
 	decreflist      []gc.GcThing
-	task            sync.WaitGroup
+	task            gc.Ref
 	namespacemutex  sync.RWMutex
 	decreflistmutex sync.Mutex
 	runnotifymutex  sync.Mutex
 	outmutex        sync.RWMutex
+	onerrormutex    sync.RWMutex
+	configmutex     sync.RWMutex
+	slotmutex       sync.RWMutex
+}
+
+//slot is variable resolved by compiler. defined is false until it's bound.
+//variables in namespace are also slot so that compiled code can refer them directly
+type slot struct {
+	value   reflect.Value
+	defined bool
 }
 
 //Incref is implements for gc.GcThing
 func (env *Env) Incref() {
-	env.task.Add(1)
+	env.task.Incref()
 }
 
 //Decref is implements for gc.GcThing
 func (env *Env) Decref() {
-	env.task.Done()
+	env.task.Decref()
 }
 
+//decrefAll decrements refcount of variables. they are collected first because releasing them may reach this Env
 func (env *Env) decrefAll() {
+	values := []reflect.Value{}
 	env.namespacemutex.RLock()
-	defer env.namespacemutex.RUnlock()
-	log.Println("decrefAll")
-	for _, v := range env.namespace {
+	for _, c := range env.namespace {
+		values = append(values, c.value)
+	}
+	env.namespacemutex.RUnlock()
+	env.slotmutex.RLock()
+	for _, s := range env.slots {
+		values = append(values, s.value)
+	}
+	env.slotmutex.RUnlock()
+	env.debug("decrefAll", logging.F("values", len(values)))
+	for _, v := range values {
 		gc.Decif(v)
 	}
 }
@@ -47,8 +78,25 @@ func (env *Env) Wait() {
 	env.task.Wait()
 }
 
+//Refcount returns refcount of env
+func (env *Env) Refcount() int {
+	return env.task.Refcount()
+}
+
+//release clears variables. it's called when refcount of env becomes zero
+func (env *Env) release() {
+	env.namespacemutex.Lock()
+	env.namespace = nil
+	env.namespacemutex.Unlock()
+	env.slotmutex.Lock()
+	env.slots = nil
+	env.slotmutex.Unlock()
+}
+
 //RunLater regist pipe to run when called Env.Run
 func (env *Env) RunLater(p pipe.Pipe) {
+	p.SetLogger(env.Logger())
+	p.SetContext(env.Context())
 	env.runnotifymutex.Lock()
 	defer env.runnotifymutex.Unlock()
 	env.runnotify[p] = true
@@ -85,26 +133,165 @@ func (env *Env) Send(v reflect.Value) bool {
 	return env.out.Send(v)
 }
 
+//SetErrorHandler sets function called when error occurs in pipe. it's shared by all Env of same root
+func (env *Env) SetErrorHandler(f func(*Error)) {
+	env.root.onerrormutex.Lock()
+	defer env.root.onerrormutex.Unlock()
+	env.root.onerror = f
+}
+
+//ReportError reports error occurred in pipe to error handler. the pipe stops after reporting.
+func (env *Env) ReportError(e *Error) {
+	env.root.onerrormutex.RLock()
+	f := env.root.onerror
+	env.root.onerrormutex.RUnlock()
+	if e.Source == nil {
+		e.Source = env.rootSource()
+	}
+	if f == nil {
+		env.Logger().Log(logging.Error, e.Message, logging.F("pos", e.Pos.Begin))
+		return
+	}
+	f(e)
+}
+
+//Importer loads module and returns its bindings. it's called by import expression
+type Importer func(env *Env, path string) (map[string]reflect.Value, error)
+
+//SetImporter sets function to load module. it's shared by all Env of same root
+func (env *Env) SetImporter(f Importer) {
+	env.root.configmutex.Lock()
+	defer env.root.configmutex.Unlock()
+	env.root.importer = f
+}
+
+//SetSource sets source code of root Env. it's attached to errors which occur in functions defined there
+func (env *Env) SetSource(s *Source) {
+	env.root.configmutex.Lock()
+	defer env.root.configmutex.Unlock()
+	env.root.source = s
+}
+
+//rootSource returns source code of root Env
+func (env *Env) rootSource() *Source {
+	env.root.configmutex.RLock()
+	defer env.root.configmutex.RUnlock()
+	return env.root.source
+}
+
+//SetContext sets context of root Env. when ctx is done, valves of pipes are closed and running functions return error
+func (env *Env) SetContext(ctx context.Context) {
+	env.root.configmutex.Lock()
+	defer env.root.configmutex.Unlock()
+	env.root.ctx = ctx
+}
+
+//Follow makes root Env use context and Logger of other whenever they are asked, instead of ones set to it.
+//module follows the Env importing it so that it stops and logs with the current evaluation
+func (env *Env) Follow(other *Env) {
+	env.root.configmutex.Lock()
+	defer env.root.configmutex.Unlock()
+	env.root.follow = other
+}
+
+//Context returns context of root Env. it's context.Background() unless SetContext or Follow is called
+func (env *Env) Context() context.Context {
+	env.root.configmutex.RLock()
+	ctx, follow := env.root.ctx, env.root.follow
+	env.root.configmutex.RUnlock()
+	if follow != nil {
+		return follow.Context()
+	}
+	return ctx
+}
+
+//SetLogger sets Logger of root Env. it's also passed to pipes when they are connected or run.
+//logs are discarded if l is nil
+func (env *Env) SetLogger(l logging.Logger) {
+	if l == nil {
+		l = logging.Discard
+	}
+	env.root.configmutex.Lock()
+	defer env.root.configmutex.Unlock()
+	env.root.logger = l
+}
+
+//Logger returns Logger of root Env. logs are discarded unless SetLogger or Follow is called
+func (env *Env) Logger() logging.Logger {
+	env.root.configmutex.RLock()
+	l, follow := env.root.logger, env.root.follow
+	env.root.configmutex.RUnlock()
+	if follow != nil {
+		return follow.Logger()
+	}
+	return l
+}
+
+func (env *Env) debug(msg string, fields ...logging.Field) {
+	env.Logger().Log(logging.Debug, msg, fields...)
+}
+
+//canceled returns error at p if context of env is done
+func (env *Env) canceled(p ast.Pos) *Error {
+	if err := env.Context().Err(); err != nil {
+		return Errorf(p, "%s", err.Error())
+	}
+	return nil
+}
+
+//Import imports bindings of module at path into env. if alias is not empty, names are prefixed with "alias."
+func (env *Env) Import(path string, alias string) error {
+	env.root.configmutex.RLock()
+	f := env.root.importer
+	env.root.configmutex.RUnlock()
+	if f == nil {
+		return fmt.Errorf("import is not available")
+	}
+	bindings, err := f(env, path)
+	if err != nil {
+		return err
+	}
+	for name, v := range bindings {
+		if alias != "" {
+			name = alias + "." + name
+		}
+		env.Define(name, v)
+	}
+	return nil
+}
+
+//Bindings returns copy of variables defined in env. variables of parent are not included
+func (env *Env) Bindings() map[string]reflect.Value {
+	env.namespacemutex.RLock()
+	defer env.namespacemutex.RUnlock()
+	ret := make(map[string]reflect.Value, len(env.namespace))
+	for k, c := range env.namespace {
+		if c.defined {
+			ret[k] = c.value
+		}
+	}
+	return ret
+}
+
 //NewEnv creates new Env. use sync.WaitGroup to wait until root environment's refcount is zero.
 func NewEnv(wg *sync.WaitGroup) *Env {
-	log.Println("newenv")
 	e := &Env{
 		parent:     nil,
-		namespace:  make(map[string]reflect.Value),
+		namespace:  make(map[string]*slot),
 		out:        pipe.NewValve(),
 		runnotify:  make(map[pipe.Pipe]bool),
 		decreflist: []gc.GcThing{},
+		ctx:        context.Background(),
+		logger:     logging.Discard,
 	}
+	e.root = e
 	wg.Add(1)
+	e.task.OnRelease(func() {
+		e.release()
+		e.debug("root env end")
+		wg.Done()
+	})
 	e.Incref()
-	go func() {
-		defer wg.Done()
-		e.Wait()
-		e.namespacemutex.Lock()
-		e.namespace = nil
-		e.namespacemutex.Unlock()
-		log.Println("root env end")
-	}()
 	return e
 }
 
@@ -115,21 +302,17 @@ func (parent *Env) ChildEnv() *Env {
 	parent.Incref()
 	e := &Env{
 		parent:     parent,
-		namespace:  make(map[string]reflect.Value),
+		root:       parent.root,
+		namespace:  make(map[string]*slot),
 		out:        parent.out,
 		runnotify:  make(map[pipe.Pipe]bool),
 		decreflist: []gc.GcThing{},
 	}
-	e.Incref()
-	go func() {
-		e.Wait()
-		e.namespacemutex.Lock()
-		e.namespace = nil
-		e.namespacemutex.Unlock()
+	e.task.OnRelease(func() {
+		e.release()
 		parent.Decref()
-		e.parent = nil
-		log.Println("child env end")
-	}()
+	})
+	e.Incref()
 	return e
 }
 
@@ -143,7 +326,7 @@ func (env *Env) RunWait(retvalue reflect.Value) {
 	env.Incref()
 	for p, b := range env.runnotify {
 		if b {
-			log.Println("run", p)
+			env.debug("run", logging.F("pipe", p))
 			p.Run(&wg)
 		}
 	}
@@ -153,9 +336,7 @@ func (env *Env) RunWait(retvalue reflect.Value) {
 	env.runnotify = nil
 	env.decreflist = nil
 	wg.Wait()
-	log.Println("wg end root")
 	gc.Waitif(retvalue)
-	log.Println("decref all root")
 	env.decrefAll()
 	env.Decref()
 }
@@ -170,7 +351,7 @@ func (env *Env) Run(retvalue reflect.Value) {
 	env.Incref()
 	for p, b := range env.runnotify {
 		if b {
-			log.Println("child run", p)
+			env.debug("run", logging.F("pipe", p))
 			p.Run(&wg)
 		}
 	}
@@ -182,12 +363,69 @@ func (env *Env) Run(retvalue reflect.Value) {
 	go func() {
 		wg.Wait()
 		gc.Waitif(retvalue)
-		log.Println("decref all")
 		env.decrefAll()
 		env.Decref()
 	}()
 }
 
+//Flush runs pipe connection registered until now and block until all connection is end.
+//Unlike RunWait, it stays in current scope so that env can be used again.
+func (env *Env) Flush() {
+	env.runnotifymutex.Lock()
+	runnotify := env.runnotify
+	env.runnotify = make(map[pipe.Pipe]bool)
+	env.runnotifymutex.Unlock()
+
+	env.decreflistmutex.Lock()
+	decreflist := env.decreflist
+	env.decreflist = []gc.GcThing{}
+	env.decreflistmutex.Unlock()
+
+	var wg sync.WaitGroup
+	for p, b := range runnotify {
+		if b {
+			env.debug("run", logging.F("pipe", p))
+			p.Run(&wg)
+		}
+	}
+	for _, t := range decreflist {
+		t.Decref()
+	}
+	wg.Wait()
+}
+
+//allocSlots prepares n slots for compiled code
+func (env *Env) allocSlots(n int) {
+	if n > 0 {
+		env.slots = make([]slot, n)
+	}
+}
+
+//up returns ancestor of env. up(0) is env itself
+func (env *Env) up(depth int) *Env {
+	for ; depth > 0; depth-- {
+		env = env.parent
+	}
+	return env
+}
+
+//loadSlot returns value of slot i. it returns false if it's not bound yet
+func (env *Env) loadSlot(i int) (reflect.Value, bool) {
+	env.slotmutex.RLock()
+	defer env.slotmutex.RUnlock()
+	s := env.slots[i]
+	return s.value, s.defined
+}
+
+//storeSlot binds v to slot i
+func (env *Env) storeSlot(i int, v reflect.Value) {
+	gc.Incif(v)
+	env.slotmutex.Lock()
+	old := env.slots[i].set(v)
+	env.slotmutex.Unlock()
+	gc.Decif(old)
+}
+
 //Lookup lookup variable
 func (env *Env) Lookup(key string) (reflect.Value, bool) {
 	env.namespacemutex.RLock()
@@ -196,9 +434,9 @@ func (env *Env) Lookup(key string) (reflect.Value, bool) {
 		return reflect.ValueOf(nil), false
 	}
 
-	if v, ok := env.namespace[key]; ok {
+	if c, ok := env.namespace[key]; ok && c.defined {
 		//gc.Incif(v)
-		return v, ok
+		return c.value, true
 	}
 	if env.parent == nil {
 		return reflect.ValueOf(nil), false
@@ -210,7 +448,7 @@ func (env *Env) Lookup(key string) (reflect.Value, bool) {
 func (env *Env) DefineBuiltin(key string, v reflect.Value) {
 	env.namespacemutex.Lock()
 	defer env.namespacemutex.Unlock()
-	env.namespace[key] = v
+	env.cellLocked(key).set(v)
 }
 
 //Define defines variable to environment
@@ -218,8 +456,7 @@ func (env *Env) Define(key string, v reflect.Value) {
 	s := env
 	for {
 		s.namespacemutex.Lock()
-		_, ok := s.namespace[key]
-		if ok {
+		if c, ok := s.namespace[key]; ok && c.defined {
 			break
 		} else {
 			s.namespacemutex.Unlock()
@@ -229,15 +466,56 @@ func (env *Env) Define(key string, v reflect.Value) {
 			break
 		}
 	}
+	gc.Incif(v)
 	if s == nil {
-		gc.Incif(v)
 		env.namespacemutex.Lock()
-		env.namespace[key] = v
+		old := env.cellLocked(key).set(v)
 		env.namespacemutex.Unlock()
+		gc.Decif(old)
 	} else {
-		gc.Incif(v)
-		gc.Decif(s.namespace[key])
-		s.namespace[key] = v
+		old := s.namespace[key].set(v)
 		s.namespacemutex.Unlock()
+		gc.Decif(old)
+	}
+}
+
+//set binds v and returns previous value
+func (s *slot) set(v reflect.Value) reflect.Value {
+	old := s.value
+	s.value = v
+	s.defined = true
+	return old
+}
+
+//cellLocked returns slot of key in namespace. it's created if not exists. namespacemutex must be locked
+func (env *Env) cellLocked(key string) *slot {
+	c, ok := env.namespace[key]
+	if !ok {
+		c = &slot{}
+		env.namespace[key] = c
 	}
+	return c
+}
+
+//cell returns slot of key in namespace for compiled code. it's not defined until the variable is bound
+func (env *Env) cell(key string) *slot {
+	env.namespacemutex.Lock()
+	defer env.namespacemutex.Unlock()
+	return env.cellLocked(key)
+}
+
+//loadCell returns value of c which is in namespace of env
+func (env *Env) loadCell(c *slot) (reflect.Value, bool) {
+	env.namespacemutex.RLock()
+	defer env.namespacemutex.RUnlock()
+	return c.value, c.defined
+}
+
+//storeCell binds v to c which is in namespace of env
+func (env *Env) storeCell(c *slot, v reflect.Value) {
+	gc.Incif(v)
+	env.namespacemutex.Lock()
+	old := c.set(v)
+	env.namespacemutex.Unlock()
+	gc.Decif(old)
 }
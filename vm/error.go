@@ -2,13 +2,30 @@ package vm
 
 import (
 	"fmt"
-	"log"
+	"os"
 
 	"../ast"
 )
 
+//Source is source code which expressions are parsed from
+type Source struct {
+	Name   string
+	Buffer string
+}
+
+//Fatal prints error to stderr and exit
 func (e Error) Fatal(buffer string) {
-	log.Fatalf("\n%sError: %s\n", show(buffer, e.Pos), e.Message)
+	fmt.Fprintln(os.Stderr, e.Show(buffer))
+	os.Exit(1)
+}
+
+//Show returns error message with position and source code.
+//if e occurred in other source, buffer is ignored
+func (e Error) Show(buffer string) string {
+	if e.Source != nil {
+		return fmt.Sprintf("file: %s\n%sError: %s", e.Source.Name, show(e.Source.Buffer, e.Pos), e.Message)
+	}
+	return fmt.Sprintf("%sError: %s", show(buffer, e.Pos), e.Message)
 }
 
 func show(buffer string, pos ast.Position) string {
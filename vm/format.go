@@ -0,0 +1,41 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"../pipe"
+)
+
+//ToString converts value to string for output. eg STDOUT
+func ToString(v reflect.Value) string {
+	if v.IsValid() {
+		if s, ok := v.Interface().(string); ok {
+			return s
+		}
+	}
+	return Inspect(v)
+}
+
+//Inspect is same as ToString except strings are quoted. it's used for elements of array and map.
+func Inspect(v reflect.Value) string {
+	if !v.IsValid() {
+		return "nil"
+	}
+	switch t := v.Interface().(type) {
+	case string:
+		return fmt.Sprintf("%q", t)
+	case []reflect.Value:
+		elems := make([]string, len(t))
+		for i, e := range t {
+			elems[i] = Inspect(e)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	case Function:
+		return "<function>"
+	case pipe.Pipe:
+		return fmt.Sprintf("<pipe %T>", t)
+	}
+	return fmt.Sprint(v.Interface())
+}
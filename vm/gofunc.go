@@ -0,0 +1,192 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var (
+	errorType = reflect.TypeOf((*error)(nil)).Elem()
+	valueType = reflect.TypeOf(reflect.Value{})
+)
+
+//NewGoFunction creates Function which calls Go function f.
+//arguments are converted to parameter types of f. int, uint, float, string, bool,
+//slices, maps, interface{} and reflect.Value are supported.
+//f can return one value, error, or value and error. returned value is converted by FromGo.
+//if f panics, the call fails with error at its position.
+func NewGoFunction(f interface{}) (Function, error) {
+	fv := reflect.ValueOf(f)
+	if fv.Kind() != reflect.Func || fv.IsNil() {
+		return nil, fmt.Errorf("%T is not function", f)
+	}
+	ft := fv.Type()
+	switch ft.NumOut() {
+	case 0, 1:
+	case 2:
+		if ft.Out(1) != errorType {
+			return nil, fmt.Errorf("second result of %s must be error", ft)
+		}
+	default:
+		return nil, fmt.Errorf("%s returns too many values", ft)
+	}
+	return NewBuiltinFunction(func(args ...reflect.Value) (ret reflect.Value, err error) {
+		in, err := goArgs(ft, args)
+		if err != nil {
+			return NIL, err
+		}
+		//panic in f is error of the call. it must not stop the program
+		defer func() {
+			if r := recover(); r != nil {
+				ret, err = NIL, fmt.Errorf("panic in %s: %v", ft, r)
+			}
+		}()
+		return goResults(fv.Call(in))
+	}), nil
+}
+
+//MustGoFunction is like NewGoFunction but panics if f is not supported
+func MustGoFunction(f interface{}) Function {
+	ret, err := NewGoFunction(f)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+//goArgs converts args to parameters of ft
+func goArgs(ft reflect.Type, args []reflect.Value) ([]reflect.Value, error) {
+	n := ft.NumIn()
+	if ft.IsVariadic() {
+		if len(args) < n-1 {
+			return nil, fmt.Errorf("wrong number of argments. expected at least %d, got %d", n-1, len(args))
+		}
+	} else if len(args) != n {
+		return nil, fmt.Errorf("wrong number of argments. expected %d, got %d", n, len(args))
+	}
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var t reflect.Type
+		if ft.IsVariadic() && i >= n-1 {
+			t = ft.In(n - 1).Elem()
+		} else {
+			t = ft.In(i)
+		}
+		v, err := ConvertTo(arg, t)
+		if err != nil {
+			return nil, fmt.Errorf("argment %d: %s", i+1, err.Error())
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+//goResults converts results of Go function
+func goResults(out []reflect.Value) (reflect.Value, error) {
+	if len(out) == 0 {
+		return NIL, nil
+	}
+	last := out[len(out)-1]
+	if last.Type() == errorType {
+		if !last.IsNil() {
+			return NIL, last.Interface().(error)
+		}
+		out = out[:len(out)-1]
+		if len(out) == 0 {
+			return NIL, nil
+		}
+	}
+	return FromGo(out[0].Interface())
+}
+
+//ConvertTo converts value of script to Go type t
+func ConvertTo(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if t == valueType {
+		return reflect.ValueOf(v), nil
+	}
+	v = Eval(v)
+	if !v.IsValid() {
+		switch t.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+		return NIL, fmt.Errorf("nil can't be %s", t)
+	}
+	if v.Type().AssignableTo(t) && t.Kind() != reflect.Interface {
+		return v, nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return reflect.ValueOf(ToGo(v)).Convert(t), nil
+		}
+		if v.Type().Implements(t) {
+			return v.Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := v.Interface().(Number); ok && n.IsInt() && n.Num().IsInt64() {
+			ret := reflect.New(t).Elem()
+			if !ret.OverflowInt(n.Num().Int64()) {
+				ret.SetInt(n.Num().Int64())
+				return ret, nil
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := v.Interface().(Number); ok && n.IsInt() && n.Sign() >= 0 && n.Num().IsUint64() {
+			ret := reflect.New(t).Elem()
+			if !ret.OverflowUint(n.Num().Uint64()) {
+				ret.SetUint(n.Num().Uint64())
+				return ret, nil
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := v.Interface().(Number); ok {
+			f, _ := n.Float64()
+			return reflect.ValueOf(f).Convert(t), nil
+		}
+	case reflect.String:
+		switch s := v.Interface().(type) {
+		case string:
+			return reflect.ValueOf(s).Convert(t), nil
+		case rune:
+			return reflect.ValueOf(string(s)).Convert(t), nil
+		}
+	case reflect.Bool:
+		if b, ok := v.Interface().(bool); ok {
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+	case reflect.Slice:
+		if arr, ok := v.Interface().([]reflect.Value); ok {
+			ret := reflect.MakeSlice(t, len(arr), len(arr))
+			for i, e := range arr {
+				c, err := ConvertTo(e, t.Elem())
+				if err != nil {
+					return NIL, err
+				}
+				ret.Index(i).Set(c)
+			}
+			return ret, nil
+		}
+	case reflect.Map:
+		if m, ok := v.Interface().(*Map); ok {
+			ret := reflect.MakeMapWithSize(t, m.Len())
+			var err error
+			m.Each(func(key, value reflect.Value) bool {
+				var k, e reflect.Value
+				if k, err = ConvertTo(key, t.Key()); err != nil {
+					return false
+				}
+				if e, err = ConvertTo(value, t.Elem()); err != nil {
+					return false
+				}
+				ret.SetMapIndex(k, e)
+				return true
+			})
+			if err != nil {
+				return NIL, err
+			}
+			return ret, nil
+		}
+	}
+	return NIL, fmt.Errorf("%s can't be %s", Inspect(v), t)
+}
@@ -0,0 +1,92 @@
+package vm
+
+import (
+	"reflect"
+
+	"../ast"
+)
+
+//getIndex converts v to index of array or string
+func getIndex(pos ast.Pos, v reflect.Value) (int, SpecialValue) {
+	if v.IsValid() {
+		if n, ok := v.Interface().(Number); ok && n.IsInt() {
+			return int(n.ToInt()), nil
+		}
+	}
+	return 0, Errorf(pos, "index must be integer. got %s", Inspect(v))
+}
+
+//IndexV returns element of array, character of string or value of map
+func IndexV(pos ast.Pos, target, index reflect.Value) (reflect.Value, SpecialValue) {
+	if !target.IsValid() {
+		return NIL, Errorf(pos, "can't index nil")
+	}
+	switch t := target.Interface().(type) {
+	case *Map:
+		if v, ok := t.Get(index); ok {
+			return v, nil
+		}
+		return NIL, nil
+	case []reflect.Value:
+		i, err := getIndex(pos, index)
+		if err != nil {
+			return NIL, err
+		}
+		if i < 0 || i >= len(t) {
+			return NIL, Errorf(pos, "index %d out of range. length is %d", i, len(t))
+		}
+		return t[i], nil
+	case string:
+		i, err := getIndex(pos, index)
+		if err != nil {
+			return NIL, err
+		}
+		runes := []rune(t)
+		if i < 0 || i >= len(runes) {
+			return NIL, Errorf(pos, "index %d out of range. length is %d", i, len(runes))
+		}
+		return reflect.ValueOf(string(runes[i])), nil
+	}
+	return NIL, Errorf(pos, "can't index %s", Inspect(target))
+}
+
+//SliceV returns part of array or string. from or to is NIL if omitted
+func SliceV(pos ast.Pos, target, from, to reflect.Value) (reflect.Value, SpecialValue) {
+	var length int
+	var runes []rune
+	isstring := false
+	if !target.IsValid() {
+		return NIL, Errorf(pos, "can't slice nil")
+	}
+	switch t := target.Interface().(type) {
+	case []reflect.Value:
+		length = len(t)
+	case string:
+		runes = []rune(t)
+		length = len(runes)
+		isstring = true
+	default:
+		return NIL, Errorf(pos, "can't slice %s", Inspect(target))
+	}
+
+	begin, end := 0, length
+	var err SpecialValue
+	if from.IsValid() {
+		if begin, err = getIndex(pos, from); err != nil {
+			return NIL, err
+		}
+	}
+	if to.IsValid() {
+		if end, err = getIndex(pos, to); err != nil {
+			return NIL, err
+		}
+	}
+	if begin < 0 || end > length || begin > end {
+		return NIL, Errorf(pos, "slice [%d:%d] out of range. length is %d", begin, end, length)
+	}
+
+	if isstring {
+		return reflect.ValueOf(string(runes[begin:end])), nil
+	}
+	return reflect.ValueOf(target.Interface().([]reflect.Value)[begin:end:end]), nil
+}
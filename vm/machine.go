@@ -0,0 +1,230 @@
+package vm
+
+import (
+	"bytes"
+	"reflect"
+
+	"../ast"
+	"../gc"
+	"../logging"
+)
+
+//Exec executes code in env. it's same as running original expressions with Run
+func Exec(code *Code, env *Env) (reflect.Value, SpecialValue) {
+	stack := make([]reflect.Value, 0, 8)
+	pop := func() reflect.Value {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		return v
+	}
+	for pc := 0; pc < len(code.ops); pc++ {
+		in := code.ops[pc]
+		switch in.op {
+		case opConst:
+			stack = append(stack, code.consts[in.a])
+		case opPop:
+			stack = stack[:len(stack)-1]
+		case opLoad:
+			value, ok := env.up(in.a).loadSlot(in.b)
+			if !ok {
+				//it's not bound yet. it may be defined outside at runtime
+				E := code.exprs[in.c]
+				if value, ok = env.Lookup(identifer(E)); !ok {
+					return NIL, Errorf(E, "%s is undefined", identifer(E))
+				}
+			}
+			gc.Incif(value)
+			env.DecrefLaterV(value)
+			stack = append(stack, value)
+		case opLoadName:
+			value, ok := env.up(in.a).Lookup(code.names[in.b])
+			if !ok {
+				return NIL, Errorf(code.exprs[in.c], "%s is undefined", code.names[in.b])
+			}
+			gc.Incif(value)
+			env.DecrefLaterV(value)
+			stack = append(stack, value)
+		case opLoadGlobal:
+			value, ok := env.up(in.a).loadCell(code.cells[in.b])
+			if !ok {
+				E := code.exprs[in.c]
+				return NIL, Errorf(E, "%s is undefined", identifer(E))
+			}
+			gc.Incif(value)
+			env.DecrefLaterV(value)
+			stack = append(stack, value)
+		case opStoreGlobal:
+			env.up(in.a).storeCell(code.cells[in.b], stack[len(stack)-1])
+		case opStore:
+			env.up(in.a).storeSlot(in.b, stack[len(stack)-1])
+		case opStoreName:
+			env.up(in.a).Define(code.names[in.b], stack[len(stack)-1])
+		case opCall:
+			E := code.exprs[in.b].(*ast.Funcall)
+			args := make([]reflect.Value, in.a)
+			for i := range args {
+				args[i] = Eval(stack[len(stack)-in.a+i])
+			}
+			stack = stack[:len(stack)-in.a]
+			fun, ok := pop().Interface().(Function)
+			if !ok {
+				return NIL, Errorf(E, "%s is not Function", E.Identifer)
+			}
+			ret, err := callIn(env, fun, E, args)
+			env.DecrefLaterV(ret)
+			if err != nil {
+				return ret, err
+			}
+			stack = append(stack, ret)
+		case opPipe:
+			args := append([]reflect.Value{}, stack[len(stack)-in.a:]...)
+			stack = stack[:len(stack)-in.a]
+			ret, err := connectPipe(code.exprs[in.b].(*ast.Pipe), args, env)
+			if err != nil {
+				return ret, err
+			}
+			stack = append(stack, ret)
+		case opBlock:
+			E := code.exprs[in.b].(*ast.Block)
+			ret := newCompiledFunction(E.FormalArgments, E.Body, code.codes[in.a], env.ChildEnv())
+			env.DecrefLater(ret)
+			stack = append(stack, reflect.ValueOf(ret))
+		case opJump:
+			pc = in.a - 1
+		case opJumpIfNot:
+			if !Condition(pop()) {
+				pc = in.a - 1
+			}
+		case opWhile:
+			ret, err := execWhile(code.exprs[in.b], code.codes[in.a], code.codes[in.a+1], env)
+			if err != nil {
+				return ret, err
+			}
+			stack = append(stack, ret)
+		case opArray:
+			arr := append([]reflect.Value{}, stack[len(stack)-in.a:]...)
+			stack = stack[:len(stack)-in.a]
+			stack = append(stack, reflect.ValueOf(arr))
+		case opMap:
+			E := code.exprs[in.b].(*ast.Map)
+			pairs := stack[len(stack)-in.a*2:]
+			m := NewMap()
+			for i := 0; i < in.a; i++ {
+				if next, e := m.Set(pairs[i*2], pairs[i*2+1]); e == nil {
+					m = next
+				} else {
+					return NIL, Errorf(E.Keys[i], "%s", e)
+				}
+			}
+			stack = stack[:len(stack)-in.a*2]
+			stack = append(stack, reflect.ValueOf(m))
+		case opInterp:
+			var buf bytes.Buffer
+			for _, part := range stack[len(stack)-in.a:] {
+				buf.WriteString(ToString(Eval(part)))
+			}
+			stack = stack[:len(stack)-in.a]
+			stack = append(stack, reflect.ValueOf(buf.String()))
+		case opIndex:
+			index := pop()
+			target := pop()
+			ret, err := IndexV(code.exprs[in.b], Eval(target), Eval(index))
+			if err != nil {
+				return ret, err
+			}
+			stack = append(stack, ret)
+		case opSlice:
+			to := pop()
+			from := pop()
+			target := pop()
+			ret, err := SliceV(code.exprs[in.b], Eval(target), Eval(from), Eval(to))
+			if err != nil {
+				return ret, err
+			}
+			stack = append(stack, ret)
+		case opEmit:
+			v := pop()
+			if !v.IsValid() {
+				return NIL, Errorf(code.exprs[in.b], "can't emit nil")
+			}
+			env.debug("emit", logging.F("value", v))
+			if !env.Send(v) {
+				return NIL, &Close{}
+			}
+		case opSkip:
+			return NIL, &Skip{}
+		case opClose:
+			env.debug("close")
+			if in.a == 0 {
+				return NIL, &Close{}
+			}
+			return pop(), &Close{}
+		case opImport:
+			E := code.exprs[in.b].(*ast.Import)
+			if err := env.Import(E.Path, E.Alias); err != nil {
+				if e, ok := err.(*Error); ok {
+					return NIL, e
+				}
+				return NIL, Errorf(E, "%s", err.Error())
+			}
+			stack = append(stack, NIL)
+		case opWait:
+			env.Wait()
+			stack = append(stack, NIL)
+		}
+	}
+	if len(stack) == 0 {
+		return NIL, nil
+	}
+	return stack[len(stack)-1], nil
+}
+
+//identifer returns name referred by RefVar or Funcall
+func identifer(e ast.Expr) string {
+	switch E := e.(type) {
+	case *ast.RefVar:
+		return E.Identifer
+	case *ast.Funcall:
+		return E.Identifer
+	}
+	return ""
+}
+
+//execWhile runs compiled while loop. condition runs in its own Env, and body runs in new Env for each iteration
+func execWhile(pos ast.Pos, cond *Code, body *Code, env *Env) (reflect.Value, SpecialValue) {
+	ret := NIL
+	cap := env.ChildEnv()
+	cap.allocSlots(cond.nslots)
+	defer func() {
+		cap.Run(NIL)
+		cap.Decref()
+	}()
+	for {
+		if err := env.canceled(pos); err != nil {
+			return ret, err
+		}
+		child := cap.ChildEnv()
+		child.allocSlots(body.nslots)
+		if c, err := Exec(cond, cap); err == nil {
+			if Condition(c) {
+				if v, err := Exec(body, child); err == nil {
+					gc.Decif(ret)
+					ret = v
+					child.Run(v)
+					child.Decref()
+				} else {
+					child.Run(v)
+					child.Decref()
+					return v, err
+				}
+			} else {
+				child.Run(NIL)
+				child.Decref()
+				return ret, nil
+			}
+		} else {
+			child.Decref()
+			return c, err
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package vm
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//Map is hash map. It's immutable, so Set and Delete return new Map.
+//Keys are kept in insertion order.
+type Map struct {
+	keys   []reflect.Value
+	values map[interface{}]reflect.Value
+}
+
+type numberKey string
+
+//hashKey converts v to comparable value to be used as key of go map
+func hashKey(v reflect.Value) (interface{}, error) {
+	if v.IsValid() {
+		switch t := v.Interface().(type) {
+		case string:
+			return t, nil
+		case bool:
+			return t, nil
+		case Number:
+			return numberKey(t.RatString()), nil
+		}
+		return nil, fmt.Errorf("%s can't be a key of map", ToString(v))
+	}
+	return nil, fmt.Errorf("nil can't be a key of map")
+}
+
+//NewMap creates empty Map
+func NewMap() *Map {
+	return &Map{
+		keys:   []reflect.Value{},
+		values: make(map[interface{}]reflect.Value),
+	}
+}
+
+//Len returns number of entries
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+//Keys returns keys in insertion order
+func (m *Map) Keys() []reflect.Value {
+	ret := make([]reflect.Value, len(m.keys))
+	copy(ret, m.keys)
+	return ret
+}
+
+//Get lookups value of key
+func (m *Map) Get(key reflect.Value) (reflect.Value, bool) {
+	k, err := hashKey(key)
+	if err != nil {
+		return NIL, false
+	}
+	v, ok := m.values[k]
+	return v, ok
+}
+
+//Set returns new Map which has value for key
+func (m *Map) Set(key, value reflect.Value) (*Map, error) {
+	k, err := hashKey(key)
+	if err != nil {
+		return nil, err
+	}
+	ret := &Map{
+		keys:   m.Keys(),
+		values: make(map[interface{}]reflect.Value, len(m.values)+1),
+	}
+	for hk, v := range m.values {
+		ret.values[hk] = v
+	}
+	if _, ok := ret.values[k]; !ok {
+		ret.keys = append(ret.keys, key)
+	}
+	ret.values[k] = value
+	return ret, nil
+}
+
+//Delete returns new Map which doesn't have key
+func (m *Map) Delete(key reflect.Value) (*Map, error) {
+	k, err := hashKey(key)
+	if err != nil {
+		return nil, err
+	}
+	ret := NewMap()
+	for _, mk := range m.keys {
+		if hk, _ := hashKey(mk); hk != k {
+			ret.keys = append(ret.keys, mk)
+			ret.values[hk] = m.values[hk]
+		}
+	}
+	return ret, nil
+}
+
+//Each calls f for each entry in insertion order until f returns false
+func (m *Map) Each(f func(key, value reflect.Value) bool) {
+	for _, key := range m.keys {
+		hk, _ := hashKey(key)
+		if !f(key, m.values[hk]) {
+			return
+		}
+	}
+}
+
+//Equal reports whether m and other have same entries
+func (m *Map) Equal(other *Map) bool {
+	if m.Len() != other.Len() {
+		return false
+	}
+	for hk, v := range m.values {
+		if ov, ok := other.values[hk]; !ok || !Equal(v, ov) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Map) String() string {
+	entries := make([]string, 0, len(m.keys))
+	m.Each(func(key, value reflect.Value) bool {
+		entries = append(entries, fmt.Sprintf("%s: %s", Inspect(key), Inspect(value)))
+		return true
+	})
+	return "{" + strings.Join(entries, ", ") + "}"
+}
@@ -41,17 +41,28 @@ func (n Number) String() string {
 	}
 }
 
+func isString(v reflect.Value) bool {
+	return v.IsValid() && v.Kind() == reflect.String
+}
+
 func SscanNumber(str string) (Number, bool) {
-	r := new(big.Rat)
-	_, err := fmt.Sscan(str, r)
-	if err != nil {
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
 		return Number{}, false
 	} else {
-		return Number{Rat: r, isfloat: strings.Contains(str, ".")}, true
+		//rational such as 1/3 is shown as float because it's not integer
+		return Number{Rat: r, isfloat: strings.Contains(str, ".") || !r.IsInt()}, true
 	}
 }
 
+//AddV adds numbers. if either is string, it concatenates them as string
 func AddV(a, b reflect.Value) (reflect.Value, error) {
+	if isString(a) || isString(b) {
+		return reflect.ValueOf(ToString(a) + ToString(b)), nil
+	}
+	if !a.IsValid() || !b.IsValid() {
+		return NIL, fmt.Errorf("Error in Add")
+	}
 	switch l := a.Interface().(type) {
 	case Number:
 		switch r := b.Interface().(type) {
@@ -128,12 +139,20 @@ func ModV(a, b reflect.Value) (reflect.Value, error) {
 }
 
 func CmpV(a, b reflect.Value) (int, error) {
+	if !a.IsValid() || !b.IsValid() {
+		return 0, fmt.Errorf("Error in Cmp")
+	}
 	switch l := a.Interface().(type) {
 	case Number:
 		switch r := b.Interface().(type) {
 		case Number:
 			return l.Cmp(r.Rat), nil
 		}
+	case string:
+		switch r := b.Interface().(type) {
+		case string:
+			return strings.Compare(l, r), nil
+		}
 	}
 	return 0, fmt.Errorf("Error in Cmp")
 }
@@ -0,0 +1,140 @@
+package vm
+
+import (
+	"reflect"
+	"sync"
+
+	"../ast"
+	"../pipe"
+)
+
+//collectvalve is Valve to collect values emitted in a function call
+type collectvalve struct {
+	values []reflect.Value
+	mutex  sync.Mutex
+}
+
+func (c *collectvalve) Send(v reflect.Value) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values = append(c.values, v)
+	return true
+}
+
+func (c *collectvalve) Receive() (reflect.Value, bool) {
+	return pipe.EOF, false
+}
+
+func (c *collectvalve) Close() {}
+
+func (c *collectvalve) Rchan() chan reflect.Value {
+	ch := make(chan reflect.Value)
+	close(ch)
+	return ch
+}
+
+//job is a call of function for one value
+type job struct {
+	value  reflect.Value
+	out    []reflect.Value
+	err    SpecialValue
+	finish chan bool
+}
+
+func (j *job) run(p ast.Pos, f Function) {
+	emitted := &collectvalve{}
+	ret, err := f.Call(p, []reflect.Value{Eval(j.value)}, emitted)
+	j.out = emitted.values
+	j.err = err
+	switch err.(type) {
+	case nil, *Close:
+		if !pipe.IsEOF(ret) {
+			j.out = append(j.out, ret)
+		}
+	}
+	j.finish <- true
+}
+
+//ParallelFilter creates Filter which calls f with n goroutines.
+//If ordered is true, outputs are in same order as inputs.
+func ParallelFilter(p ast.Pos, f Function, n int, ordered bool, env *Env) pipe.Filter {
+	f.Incref()
+	fun := func(read <-chan reflect.Value, write pipe.Valve) {
+		defer f.Decref()
+		jobs := make(chan *job)
+		finished := make(chan *job, n)
+		stop := make(chan bool)
+		var workers sync.WaitGroup
+
+		workers.Add(n)
+		for i := 0; i < n; i++ {
+			go func() {
+				defer workers.Done()
+				for j := range jobs {
+					j.run(p, f)
+					if !ordered {
+						finished <- j
+					}
+				}
+			}()
+		}
+
+		go func() {
+			defer func() {
+				close(jobs)
+				workers.Wait()
+				close(finished)
+			}()
+			for {
+				select {
+				case v, ok := <-read:
+					if !ok {
+						return
+					}
+					j := &job{value: v, finish: make(chan bool, 1)}
+					if ordered {
+						select {
+						case finished <- j:
+						case <-stop:
+							return
+						}
+					}
+					select {
+					case jobs <- j:
+					case <-stop:
+						//j is already in finished. finish it without running
+						j.finish <- true
+						return
+					}
+				case <-stop:
+					return
+				}
+			}
+		}()
+
+		stopped := false
+		for j := range finished {
+			<-j.finish
+			if stopped {
+				continue
+			}
+			for _, v := range j.out {
+				if !write.Send(v) {
+					stopped = true
+					break
+				}
+			}
+			switch E := j.err.(type) {
+			case *Close:
+				stopped = true
+			case *Error:
+				env.ReportError(E)
+				stopped = true
+			}
+			if stopped {
+				close(stop)
+			}
+		}
+	}
+	return pipe.NewFilter(fun)
+}
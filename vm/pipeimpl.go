@@ -1,16 +1,16 @@
 package vm
 
 import (
-	"log"
 	"reflect"
 
 	//	"github.com/k0kubun/pp"
 
 	"../ast"
+	"../logging"
 	"../pipe"
 )
 
-func producerFunction(p ast.Pos, f Function) pipe.Producer {
+func producerFunction(p ast.Pos, f Function, env *Env) pipe.Producer {
 	out := pipe.NewValve()
 	f.Incref()
 	go func() {
@@ -38,7 +38,8 @@ func producerFunction(p ast.Pos, f Function) pipe.Producer {
 					}
 					return
 				case *Error:
-					panic(E.Message)
+					env.ReportError(E)
+					return
 				default:
 					panic("unimplemented")
 				}
@@ -49,7 +50,7 @@ func producerFunction(p ast.Pos, f Function) pipe.Producer {
 	return ret
 }
 
-func filterFunction(p ast.Pos, f Function) pipe.Filter {
+func filterFunction(p ast.Pos, f Function, env *Env) pipe.Filter {
 	f.Incref()
 	fun := func(read <-chan reflect.Value, write pipe.Valve) {
 		defer func() {
@@ -65,7 +66,6 @@ func filterFunction(p ast.Pos, f Function) pipe.Filter {
 					}
 				}
 			} else {
-				log.Println(err)
 				switch E := err.(type) {
 				case *Skip:
 				case *Close:
@@ -75,8 +75,8 @@ func filterFunction(p ast.Pos, f Function) pipe.Filter {
 					}
 					return
 				case *Error:
-					log.Println("err filterfunction")
-					panic(E.Message)
+					env.ReportError(E)
+					return
 				default:
 					panic("unimplemented")
 				}
@@ -86,7 +86,7 @@ func filterFunction(p ast.Pos, f Function) pipe.Filter {
 	return pipe.NewFilter(fun)
 }
 
-func consumerFunction(p ast.Pos, f Function) pipe.Consumer {
+func consumerFunction(p ast.Pos, f Function, env *Env) pipe.Consumer {
 	f.Incref()
 	fun := func(r <-chan reflect.Value) reflect.Value {
 		defer func() {
@@ -99,7 +99,8 @@ func consumerFunction(p ast.Pos, f Function) pipe.Consumer {
 				case *Close:
 					return ret
 				case *Error:
-					panic(E.Message)
+					env.ReportError(E)
+					return NIL
 				default:
 					panic("unimplemented")
 				}
@@ -110,26 +111,37 @@ func consumerFunction(p ast.Pos, f Function) pipe.Consumer {
 	return pipe.NewConsumer(fun)
 }
 
+//connected logs p connected by kind of connection. eg ConnectPC
+func (env *Env) connected(kind string, p pipe.Pipe) pipe.Pipe {
+	env.debug(kind, logging.F("pipe", p))
+	return p
+}
+
 func connect(l pipe.Pipe, r pipe.Pipe, env *Env) (pipe.Pipe, bool) {
 	switch lp := l.(type) {
 	case pipe.Filter:
 		switch rp := r.(type) {
 		case pipe.Filter:
-			return pipe.ConnectFF(lp, rp), true
+			return env.connected("ConnectFF", pipe.ConnectFF(lp, rp)), true
 		case pipe.Consumer:
-			return pipe.ConnectFC(lp, rp), true
+			return env.connected("ConnectFC", pipe.ConnectFC(lp, rp)), true
 		}
 	case pipe.Producer:
 		switch rp := r.(type) {
 		case pipe.Filter:
-			return pipe.ConnectPF(lp, rp), true
+			return env.connected("ConnectPF", pipe.ConnectPF(lp, rp)), true
 		case pipe.Consumer:
-			return pipe.ConnectPC(lp, rp), true
+			return env.connected("ConnectPC", pipe.ConnectPC(lp, rp)), true
 		}
 	}
 	return nil, false
 }
 
+//AsProducer converts v to Producer. eg array, map, function
+func AsProducer(pos ast.Pos, v reflect.Value, env *Env) (pipe.Producer, bool) {
+	return asProducer(pos, v, env)
+}
+
 func asProducer(pos ast.Pos, v reflect.Value, env *Env) (pipe.Producer, bool) {
 	if v.IsValid() {
 		switch t := v.Interface().(type) {
@@ -139,23 +151,28 @@ func asProducer(pos ast.Pos, v reflect.Value, env *Env) (pipe.Producer, bool) {
 			valve := pipe.NewValve()
 			env.RunLater(t)
 			go func() {
-				result := t.Result()
-				valve.Send(result)
+				//nil result such as max of empty pipe is no value
+				if result := t.Result(); result.IsValid() {
+					valve.Send(result)
+				}
 				valve.Close()
 			}()
 			ret := pipe.NewProducer(valve)
 			env.DecrefLater(ret)
 			return ret, true
 		case Function:
-			ret := producerFunction(pos, t)
+			ret := producerFunction(pos, t, env)
 			env.DecrefLater(ret)
 			return ret, true
 		case []reflect.Value:
-			log.Println(t)
 			valve := pipe.NewValve()
 			go func() {
 				defer valve.Close()
 				for _, v := range t {
+					if !v.IsValid() {
+						env.ReportError(Errorf(pos, "can't send nil to pipe"))
+						return
+					}
 					if !valve.Send(v) {
 						return
 					}
@@ -164,6 +181,17 @@ func asProducer(pos ast.Pos, v reflect.Value, env *Env) (pipe.Producer, bool) {
 			ret := pipe.NewProducer(valve)
 			env.DecrefLater(ret)
 			return ret, true
+		case *Map:
+			valve := pipe.NewValve()
+			go func() {
+				defer valve.Close()
+				t.Each(func(key, value reflect.Value) bool {
+					return valve.Send(reflect.ValueOf([]reflect.Value{key, value}))
+				})
+			}()
+			ret := pipe.NewProducer(valve)
+			env.DecrefLater(ret)
+			return ret, true
 		default:
 			return nil, false
 		}
@@ -178,11 +206,10 @@ func asFilter(pos ast.Pos, v reflect.Value, env *Env) (pipe.Filter, bool) {
 		case pipe.Filter:
 			return t, true
 		case Function:
-			ret := filterFunction(pos, t)
+			ret := filterFunction(pos, t, env)
 			env.DecrefLater(ret)
 			return ret, true
 		case pipe.Consumer:
-			log.Println("convert consumer to filter")
 			env.RunLater(t)
 			cinput := t.NewR()
 			filter := func(r <-chan reflect.Value, w pipe.Valve) {
@@ -199,7 +226,6 @@ func asFilter(pos ast.Pos, v reflect.Value, env *Env) (pipe.Filter, bool) {
 				}
 				if !done {
 					cinput.Send(pipe.EOF)
-					log.Println("converted consumer sent EOF")
 				}
 				w.Send(t.Result())
 			}
@@ -220,7 +246,7 @@ func asConsumer(pos ast.Pos, v reflect.Value, env *Env) (pipe.Consumer, bool) {
 		case pipe.Consumer:
 			return t, true
 		case Function:
-			ret := consumerFunction(pos, t)
+			ret := consumerFunction(pos, t, env)
 			env.DecrefLater(ret)
 			return ret, true
 		default:
@@ -240,11 +266,29 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			return ret, err
 		}
 	}
+	return connectPipe(expr, args, env)
+}
+
+//connectPipe connects values of pipe expression. the pipe logs with Logger of env and stops with its context
+func connectPipe(expr *ast.Pipe, args []reflect.Value, env *Env) (reflect.Value, SpecialValue) {
+	ret, err := connectArgs(expr, args, env)
+	if err == nil {
+		if p, ok := ret.Interface().(pipe.Pipe); ok {
+			p.SetLogger(env.Logger())
+			p.SetContext(env.Context())
+			env.debug("connect", logging.F("pipe", p))
+		}
+	}
+	return ret, err
+}
+
+func connectArgs(expr *ast.Pipe, args []reflect.Value, env *Env) (reflect.Value, SpecialValue) {
 	if expr.FirstFilter {
 		if f, ok := asFilter(expr.Args[0], args[0], env); ok {
 			for i := 1; i < len(args)-1; i++ {
 				if r, ok := asFilter(expr.Args[i], args[i], env); ok {
 					f = pipe.ConnectFF(f, r)
+					env.connected("ConnectFF", f)
 					env.DecrefLater(f)
 				} else {
 					return NIL, Errorf(expr, "not filter")
@@ -253,6 +297,7 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			if expr.LastFilter {
 				if r, ok := asFilter(expr.Args[len(args)-1], args[len(args)-1], env); ok {
 					ret := pipe.ConnectFF(f, r)
+					env.connected("ConnectFF", ret)
 					env.DecrefLater(ret)
 					//env.RunLater(ret)
 					return reflect.ValueOf(ret), nil
@@ -261,6 +306,7 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			} else {
 				if r, ok := asConsumer(expr.Args[len(args)-1], args[len(args)-1], env); ok {
 					ret := pipe.ConnectFC(f, r)
+					env.connected("ConnectFC", ret)
 					env.DecrefLater(ret)
 					//env.RunLater(ret)
 					return reflect.ValueOf(ret), nil
@@ -275,6 +321,7 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			for i := 1; i < len(args)-1; i++ {
 				if r, ok := asFilter(expr.Args[i], args[i], env); ok {
 					p = pipe.ConnectPF(p, r)
+					env.connected("ConnectPF", p)
 					env.DecrefLater(p)
 				} else {
 					return NIL, Errorf(expr, "not filter")
@@ -283,6 +330,7 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			if expr.LastFilter {
 				if r, ok := asFilter(expr.Args[len(args)-1], args[len(args)-1], env); ok {
 					ret := pipe.ConnectPF(p, r)
+					env.connected("ConnectPF", ret)
 					env.DecrefLater(ret)
 					//env.RunLater(ret)
 					return reflect.ValueOf(ret), nil
@@ -291,15 +339,15 @@ func RunPipeExpr(expr *ast.Pipe, env *Env) (reflect.Value, SpecialValue) {
 			} else {
 				if r, ok := asConsumer(expr.Args[len(args)-1], args[len(args)-1], env); ok {
 					ret := pipe.ConnectPC(p, r)
+					env.connected("ConnectPC", ret)
 					env.DecrefLater(ret)
 					env.RunLater(ret)
 					return reflect.ValueOf(ret), nil
 				}
-				return NIL, Errorf(expr, "not consumer", args[len(args)-1])
+				return NIL, Errorf(expr, "not consumer %v", args[len(args)-1])
 			}
 		} else {
 			return NIL, Errorf(expr, "not producer")
 		}
 	}
-	return NIL, Errorf(expr, "pipeimpl error")
 }
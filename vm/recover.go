@@ -0,0 +1,50 @@
+package vm
+
+import (
+	"reflect"
+
+	"../ast"
+	"../gc"
+	"../pipe"
+)
+
+//RecoverFunction calls Body and routes its error to Handler.
+//Handler is called with error message and argments of Body.
+//If Handler takes only one argment, it's called with error message only.
+//Handler can return replacement value, skip or close.
+type RecoverFunction struct {
+	Body    Function
+	Handler Function
+	gc.Ref
+	Gone bool
+}
+
+//NewRecoverFunction creates RecoverFunction
+func NewRecoverFunction(body, handler Function) Function {
+	body.Incref()
+	handler.Incref()
+	r := &RecoverFunction{Body: body, Handler: handler, Gone: false}
+	r.OnRelease(func() {
+		r.Gone = true
+		body.Decref()
+		handler.Decref()
+	})
+	r.Incref()
+	return r
+}
+
+func (r *RecoverFunction) Call(context ast.Pos, args []reflect.Value, out pipe.Valve) (reflect.Value, SpecialValue) {
+	if r.Gone {
+		return NIL, Errorf(context, "called released function %v", r)
+	}
+	ret, err := r.Body.Call(context, args, out)
+	E, ok := err.(*Error)
+	if !ok {
+		return ret, err
+	}
+	hargs := []reflect.Value{reflect.ValueOf(E.Message)}
+	if u, ok := r.Handler.(*UserFunction); !ok || len(u.FormalArgments) != 1 {
+		hargs = append(hargs, args...)
+	}
+	return r.Handler.Call(context, hargs, out)
+}
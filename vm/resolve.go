@@ -0,0 +1,293 @@
+package vm
+
+import (
+	"strings"
+
+	"../ast"
+)
+
+//cscope is scope of resolver. it corresponds to one Env at runtime
+type cscope struct {
+	parent *cscope
+	slots  map[string]int
+	//named is true for top level. its variables are in namespace
+	named bool
+	//dynamic is true if import may define variables in namespace of the Env
+	dynamic bool
+	//known is variables bound at top level
+	known map[string]bool
+	//aliases of import at top level. variables prefixed with them may be defined by import
+	aliases []string
+	//open is true if import at top level may define any variable
+	open bool
+	//part is true if program is compiled in parts. variables which are not bound yet may be defined by later part
+	part bool
+	env  *Env
+}
+
+func newCScope(parent *cscope) *cscope {
+	s := &cscope{parent: parent, slots: make(map[string]int)}
+	if parent != nil {
+		s.part = parent.part
+	}
+	return s
+}
+
+//declare allocates slot for name
+func (s *cscope) declare(name string) int {
+	if i, ok := s.slots[name]; ok {
+		return i
+	}
+	i := len(s.slots)
+	s.slots[name] = i
+	return i
+}
+
+//isKnown returns true if name is variable of top level
+func (s *cscope) isKnown(name string) bool {
+	if s.known[name] {
+		return true
+	}
+	if s.env != nil {
+		_, ok := s.env.Lookup(name)
+		return ok
+	}
+	return false
+}
+
+//mayBeImported returns true if name can be defined by import at runtime
+func (s *cscope) mayBeImported(name string) bool {
+	if s.open {
+		return true
+	}
+	for _, alias := range s.aliases {
+		if strings.HasPrefix(name, alias+".") {
+			return true
+		}
+	}
+	return false
+}
+
+//enter declares slots for variables bound in exprs unless they are bound outside.
+//in part, they are bound by name instead because later part may define them at top level
+func (s *cscope) enter(exprs []ast.Expr) {
+	for _, name := range boundNames(exprs, s) {
+		if r := s.parent.bind(name); r.Scope != ast.Local && r.Scope != ast.Global && !s.part {
+			s.declare(name)
+		}
+	}
+}
+
+//bind finds variable which is updated by binding of name. Dynamic is returned if there is no such variable
+func (s *cscope) bind(name string) ast.Resolved {
+	depth := 0
+	for c := s; c != nil; c = c.parent {
+		if i, ok := c.slots[name]; ok {
+			return ast.Resolved{Scope: ast.Local, Depth: depth, Index: i}
+		}
+		if c.named {
+			if c.isKnown(name) {
+				return ast.Resolved{Scope: ast.Global, Depth: depth}
+			}
+			break
+		}
+		depth++
+	}
+	return ast.Resolved{Scope: ast.Dynamic}
+}
+
+//boundNames lists names bound directly in exprs. blocks and while loops are not included because they have own scope.
+//it marks s as dynamic if exprs has import.
+func boundNames(exprs []ast.Expr, s *cscope) []string {
+	ret := []string{}
+	var walk func(ast.Expr)
+	walkAll := func(es []ast.Expr) {
+		for _, e := range es {
+			walk(e)
+		}
+	}
+	walk = func(expr ast.Expr) {
+		switch E := expr.(type) {
+		case *ast.BindVar:
+			walk(E.Expr)
+			ret = append(ret, E.Identifer)
+		case *ast.Funcall:
+			walkAll(E.Args)
+		case *ast.Pipe:
+			walkAll(E.Args)
+		case *ast.If:
+			walkAll(E.Cond)
+			walkAll(E.True)
+			walkAll(E.Else)
+		case *ast.Array:
+			walkAll(E.Elements)
+		case *ast.StringInterp:
+			walkAll(E.Parts)
+		case *ast.Map:
+			walkAll(E.Keys)
+			walkAll(E.Values)
+		case *ast.Index:
+			walk(E.Target)
+			walk(E.Index)
+		case *ast.Slice:
+			walk(E.Target)
+			if E.From != nil {
+				walk(E.From)
+			}
+			if E.To != nil {
+				walk(E.To)
+			}
+		case *ast.Emit:
+			walkAll(E.Elements)
+		case *ast.Close:
+			walkAll(E.Ret)
+		case *ast.Import:
+			if !s.named {
+				s.dynamic = true
+			} else if E.Alias != "" {
+				s.aliases = append(s.aliases, E.Alias)
+			} else {
+				s.open = true
+			}
+		}
+	}
+	walkAll(exprs)
+	return ret
+}
+
+//resolver resolves variables of ast and collects undefined variables
+type resolver struct {
+	errors []*Error
+}
+
+//Resolve resolves variables in exprs which run at top level of env. env can be nil.
+//RefVar, BindVar and Funcall are marked with their scope, and Block and While are marked with number of slots.
+//variables bound in blocks and while loops are Local, variables bound at top level are Global.
+//It returns errors for variables which are never defined.
+func Resolve(exprs []ast.Expr, env *Env) []*Error {
+	return resolve(exprs, env, false)
+}
+
+//resolve resolves exprs. if part is true, exprs are a part of program and
+//variables which are neither bound in blocks nor defined at top level yet are Dynamic
+func resolve(exprs []ast.Expr, env *Env, part bool) []*Error {
+	top := newCScope(nil)
+	top.named = true
+	top.part = part
+	top.env = env
+	top.known = make(map[string]bool)
+	for _, name := range boundNames(exprs, top) {
+		top.known[name] = true
+	}
+	//variables of parent Env are looked up by name
+	top.dynamic = env != nil && env.parent != nil
+	r := &resolver{}
+	r.list(exprs, top)
+	return r.errors
+}
+
+//ref resolves reference of name at e
+func (r *resolver) ref(name string, e ast.Expr, s *cscope) ast.Resolved {
+	depth := 0
+	for c := s; c != nil; c = c.parent {
+		if i, ok := c.slots[name]; ok {
+			return ast.Resolved{Scope: ast.Local, Depth: depth, Index: i}
+		}
+		if c.dynamic {
+			return ast.Resolved{Scope: ast.Dynamic, Depth: depth}
+		}
+		if c.named {
+			if c.part && !c.isKnown(name) {
+				//later part may define it. it's looked up from the innermost Env
+				return ast.Resolved{Scope: ast.Dynamic}
+			}
+			if !c.isKnown(name) && !c.mayBeImported(name) {
+				r.errors = append(r.errors, Errorf(e, "%s is undefined", name))
+			}
+			return ast.Resolved{Scope: ast.Global, Depth: depth}
+		}
+		depth++
+	}
+	return ast.Resolved{Scope: ast.Dynamic}
+}
+
+//bind resolves binding of name in s. new variable is created in s if it's not bound yet
+func (r *resolver) bind(name string, s *cscope) ast.Resolved {
+	if ret := s.bind(name); ret.Scope != ast.Dynamic {
+		return ret
+	}
+	if s.named {
+		return ast.Resolved{Scope: ast.Global}
+	}
+	if s.part {
+		//it updates variable found at runtime, or defines it in s
+		return ast.Resolved{Scope: ast.Dynamic}
+	}
+	return ast.Resolved{Scope: ast.Local, Index: s.declare(name)}
+}
+
+func (r *resolver) list(exprs []ast.Expr, s *cscope) {
+	for _, e := range exprs {
+		r.expr(e, s)
+	}
+}
+
+func (r *resolver) expr(expr ast.Expr, s *cscope) {
+	switch E := expr.(type) {
+	case *ast.BindVar:
+		r.expr(E.Expr, s)
+		E.Resolved = r.bind(E.Identifer, s)
+	case *ast.RefVar:
+		E.Resolved = r.ref(E.Identifer, E, s)
+	case *ast.Funcall:
+		E.Resolved = r.ref(E.Identifer, E, s)
+		r.list(E.Args, s)
+	case *ast.Pipe:
+		r.list(E.Args, s)
+	case *ast.Block:
+		//captured Env of function, then Env of each call
+		b := newCScope(newCScope(s))
+		for _, arg := range E.FormalArgments {
+			b.declare(arg)
+		}
+		b.enter(E.Body)
+		r.list(E.Body, b)
+		E.Slots = len(b.slots)
+	case *ast.If:
+		r.list(E.Cond, s)
+		r.list(E.True, s)
+		r.list(E.Else, s)
+	case *ast.While:
+		//Env for condition, then Env of each iteration
+		cond := newCScope(s)
+		cond.enter(E.Cond)
+		body := newCScope(cond)
+		body.enter(E.Body)
+		r.list(E.Cond, cond)
+		r.list(E.Body, body)
+		E.CondSlots = len(cond.slots)
+		E.BodySlots = len(body.slots)
+	case *ast.Array:
+		r.list(E.Elements, s)
+	case *ast.StringInterp:
+		r.list(E.Parts, s)
+	case *ast.Map:
+		r.list(E.Keys, s)
+		r.list(E.Values, s)
+	case *ast.Index:
+		r.expr(E.Target, s)
+		r.expr(E.Index, s)
+	case *ast.Slice:
+		r.expr(E.Target, s)
+		if E.From != nil {
+			r.expr(E.From, s)
+		}
+		if E.To != nil {
+			r.expr(E.To, s)
+		}
+	case *ast.Emit:
+		r.list(E.Elements, s)
+	case *ast.Close:
+		r.list(E.Ret, s)
+	}
+}
@@ -29,6 +29,12 @@ type Error struct {
 	SpecialValueImpl
 	Pos     ast.Position
 	Message string
+	Source  *Source
+}
+
+//Error is implements for error
+func (e *Error) Error() string {
+	return e.Message
 }
 
 func Eval(v reflect.Value) reflect.Value {
@@ -57,23 +63,49 @@ type UserFunction struct {
 	FormalArgments []string
 	Body           []ast.Expr
 	Captured       *Env
+	code           *Code
 	gc.Ref
 	Gone bool
 }
 
 type BuiltinFunction struct {
-	funbody func(...reflect.Value) (reflect.Value, error)
+	funbody   func(...reflect.Value) (reflect.Value, error)
+	funbodyAt func(ast.Pos, ...reflect.Value) (reflect.Value, error)
+	funbodyIn func(*Env, ast.Pos, ...reflect.Value) (reflect.Value, error)
+	defined   *Env
 	gc.Ref
 	Gone bool
 }
 
 func NewBuiltinFunction(f func(...reflect.Value) (reflect.Value, error)) Function {
 	ret := &BuiltinFunction{funbody: f, Gone: false}
+	ret.OnRelease(func() {
+		ret.Gone = true
+	})
 	ret.Incref()
-	go func() {
-		ret.Wait()
+	return ret
+}
+
+//NewBuiltinFunctionAt creates builtin function which receives position of caller.
+//use it when the function reports error later. eg from pipe
+func NewBuiltinFunctionAt(f func(ast.Pos, ...reflect.Value) (reflect.Value, error)) Function {
+	ret := &BuiltinFunction{funbodyAt: f, Gone: false}
+	ret.OnRelease(func() {
 		ret.Gone = true
-	}()
+	})
+	ret.Incref()
+	return ret
+}
+
+//NewBuiltinFunctionIn creates builtin function which receives Env of caller and position of caller.
+//use it when the function creates pipes which belong to the caller's scope.
+//env is passed instead when it's not called from code. eg from pipe
+func NewBuiltinFunctionIn(env *Env, f func(*Env, ast.Pos, ...reflect.Value) (reflect.Value, error)) Function {
+	ret := &BuiltinFunction{funbodyIn: f, defined: env, Gone: false}
+	ret.OnRelease(func() {
+		ret.Gone = true
+	})
+	ret.Incref()
 	return ret
 }
 
@@ -84,19 +116,30 @@ func NewUserFunction(fargs []string, body []ast.Expr, captured *Env) Function {
 		Captured:       captured,
 		Gone:           false,
 	}
-	u.Incref()
-	go func() {
-		u.Wait()
+	u.OnRelease(func() {
 		u.Gone = true
 		captured.Decref()
-	}()
+	})
+	u.Incref()
+	return u
+}
+
+//newCompiledFunction creates UserFunction which executes code instead of body
+func newCompiledFunction(fargs []string, body []ast.Expr, code *Code, captured *Env) Function {
+	u := NewUserFunction(fargs, body, captured).(*UserFunction)
+	u.code = code
 	return u
 }
 
 func (f *BuiltinFunction) Call(context ast.Pos, args []reflect.Value, out pipe.Valve) (reflect.Value, SpecialValue) {
+	return f.callIn(f.defined, context, args)
+}
+
+//callIn calls f from code running in env
+func (f *BuiltinFunction) callIn(env *Env, context ast.Pos, args []reflect.Value) (reflect.Value, SpecialValue) {
 	if f.Gone {
 		//panic("called released builtinfunction")
-		return NIL, Errorf(context, "called released builtinfunction %s", f)
+		return NIL, Errorf(context, "called released builtinfunction %v", f)
 	}
 	for _, v := range args {
 		gc.Incif(v)
@@ -106,21 +149,41 @@ func (f *BuiltinFunction) Call(context ast.Pos, args []reflect.Value, out pipe.V
 			gc.Decif(v)
 		}
 	}()
-	ret, err := f.funbody(args...)
+	var ret reflect.Value
+	var err error
+	if f.funbodyIn != nil {
+		ret, err = f.funbodyIn(env, context, args...)
+	} else if f.funbodyAt != nil {
+		ret, err = f.funbodyAt(context, args...)
+	} else {
+		ret, err = f.funbody(args...)
+	}
 	if err != nil {
-		return ret, Errorf(context, err.Error())
+		return ret, Errorf(context, "%s", err.Error())
 	} else {
 		return ret, nil
 	}
 }
 
+//callIn calls fun from code running in env. builtin function created by NewBuiltinFunctionIn receives env
+func callIn(env *Env, fun Function, context ast.Pos, args []reflect.Value) (reflect.Value, SpecialValue) {
+	if b, ok := fun.(*BuiltinFunction); ok {
+		return b.callIn(env, context, args)
+	}
+	return fun.Call(context, args, pipe.NilValve())
+}
+
 func (this *UserFunction) Call(context ast.Pos, args []reflect.Value, out pipe.Valve) (reflect.Value, SpecialValue) {
 	if this.Gone {
-		return NIL, Errorf(context, "called released function %s", this)
+		return NIL, Errorf(context, "called released function %v", this)
 	}
 	if len(this.FormalArgments) != len(args) {
 		return NIL, Errorf(context, "invalid number of argments")
 	}
+	if E := this.Captured.canceled(context); E != nil {
+		E.Source = this.Captured.rootSource()
+		return NIL, E
+	}
 	env := this.Captured.ChildEnv()
 	env.SetOut(out)
 
@@ -133,12 +196,30 @@ func (this *UserFunction) Call(context ast.Pos, args []reflect.Value, out pipe.V
 		env.Decref()
 	}()
 
+	if this.code != nil {
+		//formal argments are the first slots
+		env.allocSlots(this.code.nslots)
+		for i := range args {
+			env.storeSlot(i, args[i])
+		}
+		if ret, err = Exec(this.code, env); err != nil {
+			if E, ok := err.(*Error); ok && E.Source == nil {
+				E.Source = this.Captured.rootSource()
+			}
+		}
+		return ret, err
+	}
+
+	//function created by Run walks body. see RunList
 	for i, name := range this.FormalArgments {
 		env.Define(name, args[i])
 	}
 
 	for _, expr := range this.Body {
 		if ret, err = Run(expr, env); err != nil {
+			if E, ok := err.(*Error); ok && E.Source == nil {
+				E.Source = this.Captured.rootSource()
+			}
 			return ret, err
 		}
 	}
@@ -1,13 +1,13 @@
 package vm
 
 import (
+	"bytes"
 	"fmt"
-	"log"
 	"reflect"
 
 	"../ast"
 	"../gc"
-	"../pipe"
+	"../logging"
 )
 
 var NIL = reflect.ValueOf(nil)
@@ -47,6 +47,8 @@ func getString(v reflect.Value) (string, error) {
 	}
 }
 
+//RunList runs exprs by walking ast. programs are compiled and run by Exec.
+//it's kept only to compare with Exec in benchmarks
 func RunList(exprs []ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 	ret := reflect.ValueOf(nil)
 	var err SpecialValue = nil
@@ -58,6 +60,7 @@ func RunList(exprs []ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 	return ret, err
 }
 
+//Run runs expr by walking ast. see RunList
 func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 	switch E := expr.(type) {
 	case *ast.Literal:
@@ -91,7 +94,7 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 			}
 			switch fun := fbody.Interface().(type) {
 			case Function:
-				ret, err := fun.Call(E, args, pipe.NilValve())
+				ret, err := callIn(env, fun, E, args)
 				env.DecrefLaterV(ret)
 				return ret, err
 			default:
@@ -102,6 +105,14 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 		}
 	case *ast.Pipe:
 		return RunPipeExpr(E, env)
+	case *ast.Import:
+		if err := env.Import(E.Path, E.Alias); err != nil {
+			if e, ok := err.(*Error); ok {
+				return NIL, e
+			}
+			return NIL, Errorf(E, "%s", err.Error())
+		}
+		return NIL, nil
 	case *ast.Wait:
 		env.Wait()
 		return NIL, nil
@@ -110,16 +121,11 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 		env.DecrefLater(ret)
 		return reflect.ValueOf(ret), nil
 	case *ast.If:
-		log.Println("ifcond run <<<")
 		cond, err := RunList(E.Cond, env)
-		log.Println("ifcond run >>>")
 		if err != nil {
 			return cond, err
 		}
-		log.Println("ifcond eval <<<")
-		b := Condition(cond)
-		log.Println("ifcond eval >>>")
-		if b {
+		if Condition(cond) {
 			return RunList(E.True, env)
 		} else {
 			return RunList(E.Else, env)
@@ -128,9 +134,13 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 		ret := NIL
 		cap := env.ChildEnv()
 		defer func() {
+			cap.Run(NIL)
 			cap.Decref()
 		}()
 		for {
+			if err := env.canceled(E); err != nil {
+				return ret, err
+			}
 			child := cap.ChildEnv()
 			if cond, err := RunList(E.Cond, cap); err == nil {
 				b := Condition(cond)
@@ -146,15 +156,15 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 						return v, err
 					}
 				} else {
-					child.Decref()
 					child.Run(NIL)
+					child.Decref()
 					return ret, nil
 				}
 			} else {
+				child.Decref()
 				return cond, err
 			}
 		}
-		return ret, nil
 	case *ast.Array:
 		arr := []reflect.Value{}
 		for _, el := range E.Elements {
@@ -165,11 +175,68 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 			}
 		}
 		return reflect.ValueOf(arr), nil
+	case *ast.StringInterp:
+		var buf bytes.Buffer
+		for _, part := range E.Parts {
+			if ret, err := Run(part, env); err == nil {
+				buf.WriteString(ToString(Eval(ret)))
+			} else {
+				return ret, err
+			}
+		}
+		return reflect.ValueOf(buf.String()), nil
+	case *ast.Map:
+		m := NewMap()
+		for i, key := range E.Keys {
+			k, err := Run(key, env)
+			if err != nil {
+				return k, err
+			}
+			v, err := Run(E.Values[i], env)
+			if err != nil {
+				return v, err
+			}
+			if next, e := m.Set(k, v); e == nil {
+				m = next
+			} else {
+				return NIL, Errorf(key, "%s", e)
+			}
+		}
+		return reflect.ValueOf(m), nil
+	case *ast.Index:
+		target, err := Run(E.Target, env)
+		if err != nil {
+			return target, err
+		}
+		index, err := Run(E.Index, env)
+		if err != nil {
+			return index, err
+		}
+		return IndexV(E, Eval(target), Eval(index))
+	case *ast.Slice:
+		target, err := Run(E.Target, env)
+		if err != nil {
+			return target, err
+		}
+		from, to := NIL, NIL
+		if E.From != nil {
+			if from, err = Run(E.From, env); err != nil {
+				return from, err
+			}
+		}
+		if E.To != nil {
+			if to, err = Run(E.To, env); err != nil {
+				return to, err
+			}
+		}
+		return SliceV(E, Eval(target), Eval(from), Eval(to))
 	case *ast.Emit:
 		for _, el := range E.Elements {
 			if ret, err := Run(el, env); err == nil {
-				log.Println("Emit", ret, err)
-
+				if !ret.IsValid() {
+					return NIL, Errorf(el, "can't emit nil")
+				}
+				env.debug("emit", logging.F("value", ret))
 				if !env.Send(ret) {
 					return NIL, &Close{}
 				}
@@ -181,7 +248,7 @@ func Run(expr ast.Expr, env *Env) (reflect.Value, SpecialValue) {
 	case *ast.Skip:
 		return NIL, &Skip{}
 	case *ast.Close:
-		log.Println("Close")
+		env.debug("close")
 		if len(E.Ret) == 0 {
 			return NIL, &Close{}
 		} else {